@@ -0,0 +1,50 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+
+// Package file provides the file system helpers shared by the packages that copy, move or replace files.
+package file
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const (
+	PrivateDir  fs.FileMode = 0o700 // PrivateDir mode means only the owner has read/write/dir access.
+	PrivateFile fs.FileMode = 0o600 // PrivateFile mode means only the owner has read/write access.
+)
+
+// Copy the content of the src file to a new dst file, which must not already exist.
+func Copy(src, dst string, perm fs.FileMode) error {
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	if err := CopyTo(out, src); err != nil {
+		_ = os.Remove(dst)
+		return err
+	}
+	return nil
+}
+
+// CopyTo copies the content of the src file to the open out file, which is then closed.
+func CopyTo(out *os.File, src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		_ = out.Close()
+		return err
+	}
+	defer in.Close()
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Temp creates a new, empty and hidden file with a unique name in the directory of the named path.
+// The file is used to build a replacement for the path, which is then renamed over it.
+func Temp(name string) (*os.File, error) {
+	return os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".dupers-*")
+}
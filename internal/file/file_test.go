@@ -0,0 +1,42 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+package file_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bengarrett/dupers/internal/file"
+	"github.com/nalgeon/be"
+)
+
+func TestCopy(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src.txt"), filepath.Join(dir, "dst.txt")
+	err := os.WriteFile(src, []byte("hello"), file.PrivateFile)
+	be.Err(t, err, nil)
+	err = file.Copy(src, dst, file.PrivateFile)
+	be.Err(t, err, nil)
+	b, err := os.ReadFile(dst)
+	be.Err(t, err, nil)
+	be.Equal(t, string(b), "hello")
+	// the dst file must not already exist
+	err = file.Copy(src, dst, file.PrivateFile)
+	be.Err(t, err, os.ErrExist)
+	err = file.Copy(filepath.Join(dir, "missing"), filepath.Join(dir, "new.txt"), file.PrivateFile)
+	be.Err(t, err, os.ErrNotExist)
+}
+
+func TestTemp(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "file.txt")
+	f1, err := file.Temp(name)
+	be.Err(t, err, nil)
+	defer f1.Close()
+	f2, err := file.Temp(name)
+	be.Err(t, err, nil)
+	defer f2.Close()
+	be.True(t, f1.Name() != f2.Name())
+	be.Equal(t, filepath.Dir(f1.Name()), filepath.Dir(name))
+	be.True(t, strings.HasPrefix(filepath.Base(f1.Name()), ".file.txt.dupers-"))
+}
@@ -0,0 +1,77 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+
+// Package glob converts the glob patterns of the .dupersignore files and the glob search into regular expressions.
+package glob
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Expr returns the regular expression of the glob pattern,
+// using the same *, **, ? and [] syntax as the .dupersignore files.
+func Expr(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '*':
+			if i+1 < len(s) && s[i+1] == '*' {
+				start := i == 0 || s[i-1] == '/'
+				i++
+				switch {
+				case start && i+1 < len(s) && s[i+1] == '/':
+					// leading or middle **/ matches zero or more directories
+					i++
+					b.WriteString(`(?:.*/)?`)
+				default:
+					b.WriteString(`.*`)
+				}
+				continue
+			}
+			b.WriteString(`[^/]*`)
+		case '?':
+			b.WriteString(`[^/]`)
+		case '[':
+			end := class(s, i)
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			set := s[i+1 : end]
+			b.WriteByte('[')
+			if strings.HasPrefix(set, "!") || strings.HasPrefix(set, "^") {
+				b.WriteByte('^')
+				set = set[1:]
+			}
+			b.WriteString(strings.ReplaceAll(set, `[`, `\[`))
+			b.WriteByte(']')
+			i = end
+		case '\\':
+			if i+1 < len(s) {
+				i++
+			}
+			b.WriteString(regexp.QuoteMeta(s[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(s[i : i+1]))
+		}
+	}
+	return b.String()
+}
+
+// class returns the index of the bracket that closes the character class at i, or -1 if it is not closed.
+func class(s string, i int) int {
+	j := i + 1
+	if j < len(s) && (s[j] == '!' || s[j] == '^') {
+		j++
+	}
+	if j < len(s) && s[j] == ']' {
+		j++
+	}
+	for ; j < len(s); j++ {
+		if s[j] == ']' {
+			return j
+		}
+	}
+	return -1
+}
@@ -0,0 +1,34 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+package glob_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/bengarrett/dupers/internal/glob"
+	"github.com/nalgeon/be"
+)
+
+func TestExpr(t *testing.T) {
+	tests := []struct {
+		glob  string
+		name  string
+		match bool
+	}{
+		{"*.txt", "file.txt", true},
+		{"*.txt", "dir/file.txt", false},
+		{"**/*.txt", "dir/sub/file.txt", true},
+		{"**/*.txt", "file.txt", true},
+		{"dir/**", "dir/sub/file.txt", true},
+		{"file?.txt", "file1.txt", true},
+		{"file[!0-9].txt", "file1.txt", false},
+		{"file[ab].txt", "fileb.txt", true},
+		{"file[.txt", "file[.txt", true},
+		{`\*.txt`, "*.txt", true},
+		{`\*.txt`, "a.txt", false},
+	}
+	for _, tt := range tests {
+		re := regexp.MustCompile("^" + glob.Expr(tt.glob) + "$")
+		be.Equal(t, re.MatchString(tt.name), tt.match)
+	}
+}
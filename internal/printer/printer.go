@@ -204,9 +204,14 @@ func Status(count, total int, m Mode) string {
 // The prompt will loop unless a y or n value is given or Ctrl-C is pressed.
 // alwaysYes will display the question but automatically input "y" on behalf of the user.
 func AskYN(question string, alwaysYes bool, recommend YN) bool {
+	return AskYNTo(os.Stdout, question, alwaysYes, recommend)
+}
+
+// AskYNTo prints the question to the writer and prompts for a yes or no reply, the same as AskYN.
+// Stderr can be used to keep the question out of the machine-readable results printed to stdout.
+func AskYNTo(w io.Writer, question string, alwaysYes bool, recommend YN) bool {
 	const no, yes = "n", "y"
 
-	w := os.Stdout
 	prompt, suffix := recommend.Define()
 	ask := fmt.Sprintf("\r%s? [%s]%s: ", question, prompt, suffix)
 	printf(w, "%s", ask)
@@ -18,6 +18,7 @@ import (
 
 	"github.com/bengarrett/dupers/internal/printer"
 	"github.com/bengarrett/dupers/pkg/cmd"
+	"github.com/bengarrett/dupers/pkg/cmd/config"
 	"github.com/bengarrett/dupers/pkg/cmd/task"
 	"github.com/bengarrett/dupers/pkg/database"
 	"github.com/bengarrett/dupers/pkg/dupe"
@@ -51,6 +52,9 @@ func printf(w io.Writer, format string, a ...any) {
 func tasks(selection string, c *dupe.Config, f cmd.Flags) error {
 	switch selection {
 	case task.Dupe_:
+		if f.Self != nil && *f.Self {
+			return task.DupeSelf(c, &f, flag.Args()...)
+		}
 		db, err := database.OpenWrite()
 		if err != nil {
 			return err
@@ -59,6 +63,10 @@ func tasks(selection string, c *dupe.Config, f cmd.Flags) error {
 			_ = db.Close()
 		}()
 		return task.Dupe(db, c, &f, flag.Args()...)
+	case task.Restore_:
+		return task.Restore(c, flag.Args()...)
+	case task.Undo_:
+		return task.Undo(c, flag.Args()...)
 	case task.Search_:
 		db, err := database.OpenRead()
 		if err != nil {
@@ -72,7 +80,8 @@ func tasks(selection string, c *dupe.Config, f cmd.Flags) error {
 		task.Backup_,
 		task.Database_, task.DB_,
 		task.Export_,
-		task.LS_:
+		task.LS_,
+		task.Report_:
 		db, err := database.OpenRead()
 		if err != nil {
 			return err
@@ -85,6 +94,7 @@ func tasks(selection string, c *dupe.Config, f cmd.Flags) error {
 		task.Clean_,
 		task.Import_,
 		task.MV_,
+		task.Rebuild_,
 		task.RM_,
 		task.Up_,
 		task.UpPlus_:
@@ -112,12 +122,22 @@ func main() {
 	}
 	flag.Parse()
 
+	file, err := config.Load()
+	if err != nil {
+		printer.ErrFatal(err)
+	}
+	if err := flg.Config(file, &cfg); err != nil {
+		printer.ErrFatal(err)
+	}
 	cfg = *flg.Aliases(&alias, &cfg)
 	if *alias.Mono || *flg.Mono {
 		color.Enable = false
 	}
+	if err := flg.Location(); err != nil {
+		printer.ErrFatal(err)
+	}
 
-	help, err := taskHelpVer(&alias, &flg)
+	help, err := taskHelpVer(&alias, &flg, file)
 	if err != nil {
 		printer.ErrFatal(err)
 	}
@@ -129,8 +149,17 @@ func main() {
 	if err := task.Directories(); err != nil {
 		printer.ErrFatal(err)
 	}
+	if err := cfg.Filter().Check(); err != nil {
+		printer.ErrFatal(err)
+	}
+	if err := cfg.CheckSize(); err != nil {
+		printer.ErrFatal(err)
+	}
 
 	selection := strings.ToLower(flag.Args()[0])
+	if file.Path != "" {
+		cfg.Debugger("configuration file: " + file.Path)
+	}
 	cfg.Debugger("command selection: " + selection)
 	if err := tasks(selection, &cfg, flg); err != nil {
 		if errors.Is(err, database.ErrNotFound) {
@@ -159,10 +188,15 @@ func unknownExit(s string) {
 }
 
 // taskHelpVer returns the help or version options.
-func taskHelpVer(a *cmd.Aliases, f *cmd.Flags) (string, error) {
+// The debug option without a command prints the options merged with the configuration file.
+func taskHelpVer(a *cmd.Aliases, f *cmd.Flags, file *config.File) (string, error) {
 	noArgs := len(flag.Args()) == 0
-	if *f.Version && *f.Debug {
-		return task.Debug(a, f)
+	if *f.Debug && (*f.Version || noArgs) {
+		s, err := task.Debug(a, f)
+		if err != nil {
+			return "", err
+		}
+		return s + task.DebugConfig(file), nil
 	}
 	if *a.Help || *f.Help {
 		selection := ""
@@ -10,10 +10,16 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"runtime"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/bengarrett/dupers/internal/printer"
+	"github.com/bengarrett/dupers/pkg/cmd/config"
+	"github.com/bengarrett/dupers/pkg/database"
 	"github.com/bengarrett/dupers/pkg/dupe"
+	"github.com/dustin/go-humanize"
 	"github.com/gookit/color"
 )
 
@@ -21,23 +27,122 @@ var (
 	ErrWindowsDir = errors.New("cannot parse the directory path")
 	ErrNilFlag    = errors.New("flags cannot be a nil value")
 	ErrNilAlias   = errors.New("aliases cannot be a nil value")
+	ErrConfigKey  = errors.New("option cannot be set in the configuration file")
+	ErrLocation   = errors.New("db and catalog options cannot be used together")
+	ErrDate       = errors.New("date must use the year-month-day format, such as 2015-01-31")
 )
 
 const (
-	Debug_   = "debug"
-	Delete_  = "delete"
-	DelPlus_ = "delete+"
-	Exact_   = "exact"
-	Fast_    = "fast"
-	Help_    = "help"
-	Mono_    = "mono"
-	Name_    = "name"
-	Quiet_   = "quiet"
-	Sensen_  = "sensen"
-	Yes_     = "yes"
-	Version_ = "version"
+	Catalog_    = "catalog"
+	DB_         = "db"
+	Debug_      = "debug"
+	Delete_     = "delete"
+	DelPlus_    = "delete+"
+	Dirs_       = "dirs"
+	DryRun_     = "dry-run"
+	Empty_      = "empty"
+	Exact_      = "exact"
+	Exclude_    = "exclude"
+	Ext_        = "ext"
+	Fast_       = "fast"
+	Format_     = "format"
+	Fuzzy_      = "fuzzy"
+	Glob_       = "glob"
+	Help_       = "help"
+	Include_    = "include"
+	Keep_       = "keep"
+	Larger_     = "larger"
+	Like_       = "like"
+	Link_       = "link"
+	MaxSize_    = "max-size"
+	MinSize_    = "min-size"
+	Mono_       = "mono"
+	Name_       = "name"
+	Newer_      = "newer"
+	Older_      = "older"
+	Prune_      = "prune"
+	Quarantine_ = "quarantine"
+	Reflink_    = "reflink"
+	Regex_      = "regex"
+	Quiet_      = "quiet"
+	Self_       = "self"
+	Sensen_     = "sensen"
+	Smaller_    = "smaller"
+	Sum_        = "sum"
+	Top_        = "top"
+	Trash_      = "trash"
+	Type_       = "type"
+	Yes_        = "yes"
+	Version_    = "version"
+	Workers_    = "workers"
 )
 
+// Top is the default number of results printed by the fuzzy search.
+const Top = 10
+
+// Globs are the values of an option that can be used more than once.
+type Globs []string
+
+// String returns the globs as a comma separated list.
+func (g *Globs) String() string {
+	if g == nil {
+		return ""
+	}
+	return strings.Join(*g, ",")
+}
+
+// Set appends the glob to the values.
+func (g *Globs) Set(s string) error {
+	*g = append(*g, s)
+	return nil
+}
+
+// Size is the value of an option that takes a number of bytes or a humanized size, such as 4KiB or 2GB.
+type Size int64
+
+// String returns the humanized size.
+func (s *Size) String() string {
+	if s == nil || *s == 0 {
+		return ""
+	}
+	return humanize.IBytes(uint64(*s))
+}
+
+// Set parses the humanized size.
+func (s *Size) Set(v string) error {
+	i, err := dupe.ParseSize(v)
+	if err != nil {
+		return err
+	}
+	*s = Size(i)
+	return nil
+}
+
+// Date is the value of an option that takes a calendar date, such as 2015-01-31.
+// The year and month on their own, such as 2015 or 2015-01, are the first day of that period.
+type Date time.Time
+
+// String returns the date using the year-month-day format.
+func (d *Date) String() string {
+	if d == nil || time.Time(*d).IsZero() {
+		return ""
+	}
+	return time.Time(*d).Format(time.DateOnly)
+}
+
+// Set parses the date, or the date and time, using the local time zone.
+func (d *Date) Set(v string) error {
+	v = strings.TrimSpace(v)
+	for _, layout := range []string{time.DateOnly, time.DateTime, time.RFC3339, "2006-01", "2006"} {
+		t, err := time.ParseInLocation(layout, v, time.Local)
+		if err == nil {
+			*d = Date(t)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrDate, v)
+}
+
 // Aliases are single letter options for commands.
 type Aliases struct {
 	Debug    *bool `usage:"alias for debug"`
@@ -83,21 +188,51 @@ func (a *Aliases) Define() {
 
 // Flags provide options for both the commands and the program.
 type Flags struct {
-	Exact    *bool `usage:"match case"`
-	Filename *bool `usage:"search for filenames, and ignore directories"`
-	Lookup   *bool `usage:"query the database for a much faster match, the results\n\t may be stale as it does not detect file changes on\n\t your system"` //nolint:lll
-	Rm       *bool `usage:"delete the duplicate files found in the\n\t <directory to check>"`
-	RmPlus   *bool `usage:"delete the duplicate files and remove empty directories\n\t from the <directory to check>"`
-	Sensen   *bool `usage:"delete directories in the <directory to check> except\n\t directories containing unique Windows programs and\n\t assets"` //nolint:lll
+	Dirs       *bool   `usage:"report the identical and subset directories in the\n\t <directory to check> and the buckets, instead of files"`
+	Empty      *bool   `usage:"use the zero byte files, which are otherwise skipped"`
+	Exact      *bool   `usage:"match case"`
+	Exclude    *Globs  `usage:"skip the files and directories matching this glob, it can be\n\t used more than once and uses the .dupersignore syntax"` //nolint:lll
+	Ext        *string `usage:"only search for the files using these comma separated\n\t extensions, such as jpg,png"`
+	Filename   *bool   `usage:"search for filenames, and ignore directories"`
+	Format     *string `usage:"print the results in a machine-readable format,\n\t either json, ndjson or csv"`
+	Fuzzy      *bool   `usage:"rank the stored files by how closely they resemble the\n\t search expression, to find the misspelled filenames"`
+	Glob       *bool   `usage:"search using a glob pattern such as *.jpg or photos/**/*.png"`
+	Include    *Globs  `usage:"only use the files matching this glob, it can be used more\n\t than once and uses the .dupersignore syntax"`                                                                            //nolint:lll
+	Keep       *string `usage:"with a delete option, keep one file from each group of\n\t duplicates and delete every other copy, either oldest,\n\t newest, shortest, longest, bucket:<directory> or glob:<pattern>"` //nolint:lll
+	Larger     *Size   `usage:"only search for the files larger than this size, such as 1GB"`
+	Like       *bool   `usage:"search for the files with the same content as the named local file"`
+	Link       *string `usage:"replace the duplicate files in the <directory to check>\n\t with either hard or sym links to the matching files"`
+	Lookup     *bool   `usage:"query the database for a much faster match, the results\n\t may be stale as it does not detect file changes on\n\t your system"` //nolint:lll
+	MaxSize    *Size   `usage:"skip the files larger than this size, such as 2GB"`
+	MinSize    *Size   `usage:"skip the files smaller than this size, such as 4KiB"`
+	Newer      *Date   `usage:"only search for the files modified after this date, such as 2015-01-31"`
+	Older      *Date   `usage:"only search for the files modified before this date, such as 2015-01-31"`
+	Prune      *bool   `usage:"with the clean command, also remove the stored items skipped\n\t by the .dupersignore files of the buckets"`
+	Quarantine *string `usage:"with a delete option, move the files into a dated directory\n\t within this directory instead of deleting them"`
+	Regex      *bool   `usage:"search using a regular expression"`
+	Reflink    *bool   `usage:"share the data of the duplicate files in the <directory to\n\t check> with the matching files, using copy-on-write\n\t reflinks on btrfs or xfs file systems"`
+	Rm         *bool   `usage:"delete the duplicate files found in the\n\t <directory to check>"`
+	RmPlus     *bool   `usage:"delete the duplicate files and remove empty directories\n\t from the <directory to check>"`
+	Self       *bool   `usage:"find identical files within the <directory to check>,\n\t the database and buckets are not used"`
+	Sensen     *bool   `usage:"delete directories in the <directory to check> except\n\t directories containing unique Windows programs and\n\t assets"` //nolint:lll
+	Smaller    *Size   `usage:"only search for the files smaller than this size, such as 4KiB"`
+	Sum        *bool   `usage:"search for the files matching a SHA-256 checksum"`
+	Top        *int    `usage:"number of results to print with the fuzzy option"`
+	Trash      *bool   `usage:"with a delete option, move the files to the desktop trash\n\t instead of deleting them"`
+	Type       *string `usage:"only search for the files with this type of content,\n\t either image, audio, video, archive or document"`
+	Workers    *int    `usage:"number of files to read and hash at the same time,\n\t the default is the number of CPUs"`
 
 	// global options
 
-	Debug   *bool `usage:"debug enables verbose output showing all program activities"`
-	Help    *bool `usage:"print help"`
-	Mono    *bool `usage:"monochrome mode to remove all color output"`
-	Quiet   *bool `usage:"quiet mode suppresses non-essential output and progress indicators"`
-	Yes     *bool `usage:"assume yes for any user prompts"`
-	Version *bool `usage:"version and information for this program"`
+	Catalog *string `usage:"use the named catalog, a separate database kept next to the\n\t default database"`
+	DB      *string `usage:"path of the database file to use, which overrides the\n\t DUPERS_DB environment variable"`
+	Debug   *bool   `usage:"debug enables verbose output showing all program activities"`
+	DryRun  *bool   `usage:"print what would be removed, renamed or cleaned without\n\t making any changes"`
+	Help    *bool   `usage:"print help"`
+	Mono    *bool   `usage:"monochrome mode to remove all color output"`
+	Quiet   *bool   `usage:"quiet mode suppresses non-essential output and progress indicators"`
+	Yes     *bool   `usage:"assume yes for any user prompts"`
+	Version *bool   `usage:"version and information for this program"`
 }
 
 // Usage of the command flags.
@@ -119,18 +254,118 @@ func (f *Flags) Define() {
 	if f == nil {
 		return
 	}
+	f.Catalog = flag.String(Catalog_, "", f.Usage("Catalog"))
+	f.DB = flag.String(DB_, "", f.Usage("DB"))
 	f.Debug = flag.Bool(Debug_, false, f.Usage("Debug"))
+	f.Dirs = flag.Bool(Dirs_, false, f.Usage("Dirs"))
+	f.DryRun = flag.Bool(DryRun_, false, f.Usage("DryRun"))
+	f.Empty = flag.Bool(Empty_, false, f.Usage("Empty"))
 	f.Exact = flag.Bool(Exact_, false, f.Usage("Exact"))
+	f.Exclude = new(Globs)
+	flag.Var(f.Exclude, Exclude_, f.Usage("Exclude"))
+	f.Ext = flag.String(Ext_, "", f.Usage("Ext"))
 	f.Filename = flag.Bool(Name_, false, f.Usage("Filename"))
+	f.Format = flag.String(Format_, "", f.Usage("Format"))
+	f.Fuzzy = flag.Bool(Fuzzy_, false, f.Usage("Fuzzy"))
+	f.Glob = flag.Bool(Glob_, false, f.Usage("Glob"))
 	f.Help = flag.Bool(Help_, false, f.Usage("Help")) // only used in certain circumstances
+	f.Include = new(Globs)
+	flag.Var(f.Include, Include_, f.Usage("Include"))
+	f.Keep = flag.String(Keep_, "", f.Usage("Keep"))
+	f.Larger = new(Size)
+	flag.Var(f.Larger, Larger_, f.Usage("Larger"))
+	f.Like = flag.Bool(Like_, false, f.Usage("Like"))
+	f.Link = flag.String(Link_, "", f.Usage("Link"))
 	f.Lookup = flag.Bool(Fast_, false, f.Usage("Lookup"))
+	f.MaxSize = new(Size)
+	flag.Var(f.MaxSize, MaxSize_, f.Usage("MaxSize"))
+	f.MinSize = new(Size)
+	flag.Var(f.MinSize, MinSize_, f.Usage("MinSize"))
 	f.Mono = flag.Bool(Mono_, false, f.Usage("Mono"))
+	f.Newer = new(Date)
+	flag.Var(f.Newer, Newer_, f.Usage("Newer"))
+	f.Older = new(Date)
+	flag.Var(f.Older, Older_, f.Usage("Older"))
+	f.Prune = flag.Bool(Prune_, false, f.Usage("Prune"))
+	f.Quarantine = flag.String(Quarantine_, "", f.Usage("Quarantine"))
+	f.Reflink = flag.Bool(Reflink_, false, f.Usage("Reflink"))
+	f.Regex = flag.Bool(Regex_, false, f.Usage("Regex"))
 	f.Quiet = flag.Bool(Quiet_, false, f.Usage("Quiet"))
+	f.Self = flag.Bool(Self_, false, f.Usage("Self"))
 	f.Sensen = flag.Bool(Sensen_, false, f.Usage("Sensen"))
+	f.Smaller = new(Size)
+	flag.Var(f.Smaller, Smaller_, f.Usage("Smaller"))
+	f.Sum = flag.Bool(Sum_, false, f.Usage("Sum"))
 	f.Rm = flag.Bool(Delete_, false, f.Usage("Rm"))
 	f.RmPlus = flag.Bool(DelPlus_, false, f.Usage("RmPlus"))
+	f.Top = flag.Int(Top_, Top, f.Usage("Top"))
+	f.Trash = flag.Bool(Trash_, false, f.Usage("Trash"))
+	f.Type = flag.String(Type_, "", f.Usage("Type"))
 	f.Yes = flag.Bool(Yes_, false, f.Usage("Yes"))
 	f.Version = flag.Bool(Version_, false, f.Usage("Version"))
+	f.Workers = flag.Int(Workers_, runtime.NumCPU(), f.Usage("Workers"))
+}
+
+// Configurable are the options that can be set in the configuration file.
+// The options that remove or change files, or that choose which files are removed, are left to the command-line.
+// So is the output format, as a saved value would silently change the output of every command.
+// The quarantine and trash options can be set, as they only make the removals recoverable.
+func Configurable() []string {
+	return []string{
+		Catalog_, DB_, Empty_, Exact_, Exclude_, Fast_, Include_,
+		MaxSize_, MinSize_, Mono_, Name_, Quarantine_, Quiet_, Trash_, Workers_,
+	}
+}
+
+// Config sets the options and the bucket settings from the configuration file.
+// The options passed on the command-line override the values in the file.
+// It should be used after flag.Parse and before Aliases.
+func (f *Flags) Config(file *config.File, c *dupe.Config) error {
+	if file == nil || c == nil {
+		return nil
+	}
+	set := map[string]bool{}
+	flag.Visit(func(fl *flag.Flag) {
+		set[fl.Name] = true
+	})
+	// the database location of the file is replaced by any location on the command-line or environment
+	located := set[DB_] || set[Catalog_] || os.Getenv(database.Env) != ""
+	for _, key := range file.Keys() {
+		if !slices.Contains(Configurable(), key) {
+			return fmt.Errorf("%w: %s", ErrConfigKey, key)
+		}
+		if set[key] || located && (key == DB_ || key == Catalog_) {
+			continue
+		}
+		for _, val := range file.Values[key] {
+			if err := flag.Set(key, val); err != nil {
+				return fmt.Errorf("%w, %s: %w", config.ErrValue, key, err)
+			}
+		}
+	}
+	c.Options = file.Buckets
+	return nil
+}
+
+// Location uses the database file of the db or the catalog options.
+// Without either option the database is set by the DUPERS_DB environment variable or the default location.
+func (f *Flags) Location() error {
+	db, catalog := "", ""
+	if f.DB != nil {
+		db = *f.DB
+	}
+	if f.Catalog != nil {
+		catalog = *f.Catalog
+	}
+	switch {
+	case db != "" && catalog != "":
+		return ErrLocation
+	case db != "":
+		return database.Use(db)
+	case catalog != "":
+		return database.Catalog(catalog)
+	}
+	return nil
 }
 
 // Aliases parses the command aliases and flags, configuring both Flags and dupe.Config.
@@ -150,6 +385,39 @@ func (f *Flags) Aliases(a *Aliases, c *dupe.Config) *dupe.Config {
 		*f.Yes = true
 		c.Yes = true
 	}
+	if f.DryRun != nil {
+		c.DryRun = *f.DryRun
+	}
+	if f.Workers != nil {
+		c.Workers = *f.Workers
+	}
+	if f.Keep != nil {
+		c.Keep = *f.Keep
+	}
+	if f.Prune != nil {
+		c.Prune = *f.Prune
+	}
+	if f.Quarantine != nil {
+		c.Quarantine = *f.Quarantine
+	}
+	if f.Trash != nil {
+		c.Trash = *f.Trash
+	}
+	if f.MinSize != nil {
+		c.MinSize = int64(*f.MinSize)
+	}
+	if f.MaxSize != nil {
+		c.MaxSize = int64(*f.MaxSize)
+	}
+	if f.Empty != nil {
+		c.Empty = *f.Empty
+	}
+	if f.Include != nil {
+		c.Include = *f.Include
+	}
+	if f.Exclude != nil {
+		c.Exclude = *f.Exclude
+	}
 	// command flags
 	if *a.Exact {
 		*f.Exact = true
@@ -186,6 +454,10 @@ func (f *Flags) aliases() {
 			*f.Help = true
 		case "-y", "-yes", "--yes":
 			*f.Yes = true
+		case "-dry-run", "--dry-run":
+			if f.DryRun != nil {
+				*f.DryRun = true
+			}
 		case "-v", "-version", "--version":
 			*f.Version = true
 		default:
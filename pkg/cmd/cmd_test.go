@@ -2,10 +2,13 @@
 package cmd_test
 
 import (
+	"errors"
 	"log"
 	"testing"
 
 	"github.com/bengarrett/dupers/pkg/cmd"
+	"github.com/bengarrett/dupers/pkg/cmd/config"
+	"github.com/bengarrett/dupers/pkg/dupe"
 	"github.com/gookit/color"
 )
 
@@ -124,3 +127,77 @@ func TestSearchSummary(t *testing.T) {
 		})
 	}
 }
+
+func TestSize(t *testing.T) {
+	var s cmd.Size
+	if err := s.Set("4KiB"); err != nil {
+		t.Errorf("Size.Set() error = %v, want nil", err)
+	}
+	if s != 4096 || s.String() != "4.0 KiB" {
+		t.Errorf("Size.Set() = %d %q, want 4096", s, s.String())
+	}
+	if err := s.Set("four"); err == nil {
+		t.Errorf("Size.Set() should return an error")
+	}
+}
+
+func TestDate(t *testing.T) {
+	var d cmd.Date
+	if d.String() != "" {
+		t.Errorf("Date.String() = %q, want an empty string", d.String())
+	}
+	for _, v := range []string{"2015", "2015-01", "2015-01-01", "2015-01-01 00:00:00"} {
+		if err := d.Set(v); err != nil {
+			t.Errorf("Date.Set(%q) error = %v, want nil", v, err)
+		}
+		if d.String() != "2015-01-01" {
+			t.Errorf("Date.Set(%q) = %q, want 2015-01-01", v, d.String())
+		}
+	}
+	if err := d.Set("01/31/2015"); !errors.Is(err, cmd.ErrDate) {
+		t.Errorf("Date.Set() error = %v, want %v", err, cmd.ErrDate)
+	}
+}
+
+func TestFlags_Config(t *testing.T) {
+	f, c := cmd.Flags{}, dupe.Config{}
+	if err := f.Config(nil, &c); err != nil {
+		t.Errorf("Flags.Config() error = %v, want nil", err)
+	}
+	file := config.File{
+		Values:  map[string][]string{cmd.Delete_: {"true"}},
+		Buckets: map[string]dupe.Options{"/tmp": {ReadOnly: true}},
+	}
+	if err := f.Config(&file, &c); !errors.Is(err, cmd.ErrConfigKey) {
+		t.Errorf("Flags.Config() error = %v, want %v", err, cmd.ErrConfigKey)
+	}
+	// a saved keep policy would change a plain -delete into removing the copies in the buckets
+	for _, key := range []string{cmd.Keep_, cmd.Format_} {
+		file.Values = map[string][]string{key: {"oldest"}}
+		if err := f.Config(&file, &c); !errors.Is(err, cmd.ErrConfigKey) {
+			t.Errorf("Flags.Config() %s error = %v, want %v", key, err, cmd.ErrConfigKey)
+		}
+	}
+	if c.Keep != "" {
+		t.Errorf("Flags.Config() keep = %q, want an empty policy", c.Keep)
+	}
+	file.Values = nil
+	if err := f.Config(&file, &c); err != nil {
+		t.Errorf("Flags.Config() error = %v, want nil", err)
+	}
+	if !c.Option("/tmp").ReadOnly {
+		t.Errorf("Flags.Config() should set the bucket options")
+	}
+}
+
+func TestFlags_Location(t *testing.T) {
+	f := cmd.Flags{}
+	if err := f.Location(); err != nil {
+		t.Errorf("Flags.Location() error = %v, want nil", err)
+	}
+	db, catalog := "test.db", "test"
+	f.DB, f.Catalog = &db, &catalog
+	if err := f.Location(); !errors.Is(err, cmd.ErrLocation) {
+		t.Errorf("Flags.Location() error = %v, want %v", err, cmd.ErrLocation)
+	}
+}
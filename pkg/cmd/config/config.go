@@ -0,0 +1,308 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+
+// Package config reads the configuration file of the default options and the bucket settings.
+//
+// The file uses a subset of TOML, with key = value pairs of strings, integers, booleans and arrays.
+// The keys are the names of the command-line options, and each bucket has a table of settings.
+//
+//	quiet = true
+//	exclude = ["*.tmp", "node_modules/"]
+//
+//	[buckets."/mnt/archive"]
+//	read-only = true
+//	archives = true
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bengarrett/dupers/pkg/database"
+	"github.com/bengarrett/dupers/pkg/dupe"
+)
+
+const (
+	Filename = "config.toml" // Filename of the configuration file, kept in the same directory as the database.
+	Archives = "archives"    // Archives is the bucket setting to also hash the content of the archive files.
+	ReadOnly = "read-only"   // ReadOnly is the bucket setting to never walk, clean or change the bucket.
+
+	table = "buckets."
+)
+
+var (
+	ErrKey    = errors.New("configuration key is unknown")
+	ErrSyntax = errors.New("configuration syntax is invalid")
+	ErrValue  = errors.New("configuration value is invalid")
+)
+
+// File is a parsed configuration file.
+type File struct {
+	Path    string                  // Path of the configuration file, empty when there is no file.
+	Values  map[string][]string     // Values of the options keyed by the option name, an array can have many values.
+	Buckets map[string]dupe.Options // Buckets are the settings of the buckets keyed by the absolute directory path.
+}
+
+// Keys returns the sorted names of the options in the file.
+func (f *File) Keys() []string {
+	if f == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(f.Values))
+	for key := range f.Values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Path returns the location of the configuration file within the user config directory.
+// The directory is not created, as a missing configuration file is an empty configuration.
+func Path() (string, error) {
+	dir, err := database.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, Filename), nil
+}
+
+// Load reads the configuration file from the user config directory.
+func Load() (*File, error) {
+	name, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	return Read(name)
+}
+
+// Read parses the named configuration file.
+// A file that does not exist returns an empty configuration.
+func Read(name string) (*File, error) {
+	file, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &File{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	f, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("%w, in %s", err, name)
+	}
+	f.Path = name
+	return f, nil
+}
+
+// Parse the configuration from the reader.
+func Parse(r io.Reader) (*File, error) {
+	f := File{
+		Values:  make(map[string][]string),
+		Buckets: make(map[string]dupe.Options),
+	}
+	scanner := bufio.NewScanner(r)
+	bucket, row := "", 0
+	for scanner.Scan() {
+		row++
+		start := row
+		line := uncomment(scanner.Text())
+		// an array can be split over many lines
+		for open(line) && scanner.Scan() {
+			row++
+			line += " " + uncomment(scanner.Text())
+		}
+		if line == "" {
+			continue
+		}
+		var err error
+		if strings.HasPrefix(line, "[") {
+			bucket, err = header(line)
+		} else {
+			err = f.set(bucket, line)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w, line %d: %s", err, start, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// set parses the key = value line and saves it to the options or the settings of the bucket.
+func (f *File) set(bucket, line string) error {
+	key, values, err := pair(line)
+	if err != nil {
+		return err
+	}
+	if bucket == "" {
+		if _, ok := f.Values[key]; ok {
+			return fmt.Errorf("%w: %s is used more than once", ErrKey, key)
+		}
+		f.Values[key] = values
+		return nil
+	}
+	if len(values) != 1 || values[0] != "true" && values[0] != "false" {
+		return fmt.Errorf("%w: %s must be true or false", ErrValue, key)
+	}
+	opts, val := f.Buckets[bucket], values[0] == "true"
+	switch key {
+	case Archives:
+		opts.Archives = val
+	case ReadOnly:
+		opts.ReadOnly = val
+	default:
+		return fmt.Errorf("%w: %s", ErrKey, key)
+	}
+	f.Buckets[bucket] = opts
+	return nil
+}
+
+// header returns the absolute directory of the [buckets."<directory>"] table.
+func header(line string) (string, error) {
+	if !strings.HasSuffix(line, "]") {
+		return "", ErrSyntax
+	}
+	name := strings.TrimSpace(line[1 : len(line)-1])
+	if !strings.HasPrefix(name, table) {
+		return "", fmt.Errorf("%w: only the %q tables are supported", ErrKey, table+"<directory>")
+	}
+	dir, err := scalar(strings.TrimSpace(name[len(table):]))
+	if err != nil {
+		return "", err
+	}
+	if dir == "~" || strings.HasPrefix(dir, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, dir[1:])
+	}
+	return database.Abs(dir)
+}
+
+// pair splits the key = value line and returns the key and the values.
+func pair(line string) (string, []string, error) {
+	key, val, ok := strings.Cut(line, "=")
+	key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+	if !ok || key == "" || strings.ContainsFunc(key, invalid) {
+		return "", nil, ErrSyntax
+	}
+	if !strings.HasPrefix(val, "[") {
+		s, err := scalar(val)
+		if err != nil {
+			return "", nil, err
+		}
+		return key, []string{s}, nil
+	}
+	if !strings.HasSuffix(val, "]") {
+		return "", nil, ErrSyntax
+	}
+	values := []string{}
+	for _, item := range split(val[1 : len(val)-1]) {
+		s, err := scalar(item)
+		if err != nil {
+			return "", nil, err
+		}
+		values = append(values, s)
+	}
+	return key, values, nil
+}
+
+// invalid returns true if the rune cannot be used in a bare key.
+func invalid(r rune) bool {
+	return (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '-' && r != '_'
+}
+
+// scalar returns the string, integer or boolean value as a string.
+func scalar(val string) (string, error) {
+	const quotes = 2
+	switch {
+	case val == "true", val == "false":
+		return val, nil
+	case len(val) >= quotes && val[0] == '"' && val[len(val)-1] == '"':
+		s, err := strconv.Unquote(val)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s", ErrValue, val)
+		}
+		return s, nil
+	case len(val) >= quotes && val[0] == '\'' && val[len(val)-1] == '\'':
+		// literal strings have no escapes, which suits windows paths
+		s := val[1 : len(val)-1]
+		if strings.Contains(s, "'") {
+			return "", fmt.Errorf("%w: %s", ErrValue, val)
+		}
+		return s, nil
+	}
+	i, err := strconv.ParseInt(strings.ReplaceAll(val, "_", ""), 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrValue, val)
+	}
+	return strconv.FormatInt(i, 10), nil
+}
+
+// split the comma separated items of an array, ignoring any commas within the quoted strings.
+func split(s string) []string {
+	items := []string{}
+	quote, start := rune(0), 0
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote && (quote == '\'' || !escaped(s[:i])) {
+				quote = 0
+			}
+		case r == '"', r == '\'':
+			quote = r
+		case r == ',':
+			items = append(items, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	// a trailing comma is allowed
+	if last := strings.TrimSpace(s[start:]); last != "" {
+		items = append(items, last)
+	}
+	return items
+}
+
+// uncomment removes the # comment and the surrounding whitespace from the line.
+func uncomment(line string) string {
+	quote := rune(0)
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote && (quote == '\'' || !escaped(line[:i])) {
+				quote = 0
+			}
+		case r == '"', r == '\'':
+			quote = r
+		case r == '#':
+			return strings.TrimSpace(line[:i])
+		}
+	}
+	return strings.TrimSpace(line)
+}
+
+// open returns true if the line has an array that is not closed.
+func open(line string) bool {
+	_, val, ok := strings.Cut(line, "=")
+	if !ok {
+		return false
+	}
+	val = strings.TrimSpace(val)
+	return strings.HasPrefix(val, "[") && !strings.HasSuffix(val, "]")
+}
+
+// escaped returns true if the string ends with an odd number of backslashes.
+func escaped(s string) bool {
+	n := len(s) - len(strings.TrimRight(s, `\`))
+	return n%2 == 1
+}
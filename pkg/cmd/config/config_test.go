@@ -0,0 +1,109 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bengarrett/dupers/pkg/cmd/config"
+	"github.com/bengarrett/dupers/pkg/database"
+	"github.com/nalgeon/be"
+)
+
+const example = `# dupers configuration
+quiet = true
+workers = 4 # inline comment
+min-size = "4KiB"
+quarantine = 'C:\quarantine'
+exclude = [
+	"*.tmp",
+	"node_modules/", # trailing comma
+]
+include = ["#hash", "a,b"]
+
+[buckets."/mnt/archive"]
+read-only = true
+archives = true
+
+[ buckets.'/mnt/photos' ]
+archives = true
+`
+
+func TestParse(t *testing.T) {
+	f, err := config.Parse(strings.NewReader(example))
+	be.Err(t, err, nil)
+	be.Equal(t, f.Keys(), []string{"exclude", "include", "min-size", "quarantine", "quiet", "workers"})
+	be.Equal(t, f.Values["quiet"], []string{"true"})
+	be.Equal(t, f.Values["workers"], []string{"4"})
+	be.Equal(t, f.Values["min-size"], []string{"4KiB"})
+	be.Equal(t, f.Values["quarantine"], []string{`C:\quarantine`})
+	be.Equal(t, f.Values["exclude"], []string{"*.tmp", "node_modules/"})
+	be.Equal(t, f.Values["include"], []string{"#hash", "a,b"})
+	archive, err := database.Abs("/mnt/archive")
+	be.Err(t, err, nil)
+	photos, err := database.Abs("/mnt/photos")
+	be.Err(t, err, nil)
+	be.Equal(t, len(f.Buckets), 2)
+	be.True(t, f.Buckets[archive].ReadOnly)
+	be.True(t, f.Buckets[archive].Archives)
+	be.True(t, !f.Buckets[photos].ReadOnly)
+	be.True(t, f.Buckets[photos].Archives)
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  error
+	}{
+		{"quiet", config.ErrSyntax},
+		{"quiet = yes", config.ErrValue},
+		{"quiet = true\nquiet = false", config.ErrKey},
+		{`name = "unclosed`, config.ErrValue},
+		{"exclude = [\"*.tmp\"", config.ErrSyntax},
+		{"[options]", config.ErrKey},
+		{"[buckets.\"/tmp\"]\nread-only = 1", config.ErrValue},
+		{"[buckets.\"/tmp\"]\nhidden = true", config.ErrKey},
+	}
+	for _, tt := range tests {
+		_, err := config.Parse(strings.NewReader(tt.input))
+		be.Err(t, err, tt.want)
+	}
+}
+
+func TestRead(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, config.Filename)
+	f, err := config.Read(name)
+	be.Err(t, err, nil)
+	be.Equal(t, f.Path, "")
+	be.Equal(t, len(f.Keys()), 0)
+	err = os.WriteFile(name, []byte(example), database.PrivateFile)
+	be.Err(t, err, nil)
+	f, err = config.Read(name)
+	be.Err(t, err, nil)
+	be.Equal(t, f.Path, name)
+	be.Equal(t, len(f.Keys()), 6)
+	err = os.WriteFile(name, []byte("mono = on\n"), database.PrivateFile)
+	be.Err(t, err, nil)
+	_, err = config.Read(name)
+	be.Err(t, err, config.ErrValue)
+	be.True(t, strings.Contains(err.Error(), "line 1"))
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("AppData", dir)
+	name, err := config.Path()
+	be.Err(t, err, nil)
+	be.True(t, strings.HasPrefix(name, dir))
+	f, err := config.Load()
+	be.Err(t, err, nil)
+	be.Equal(t, len(f.Keys()), 0)
+	// a missing configuration file does not create the dupers directory
+	_, err = os.Stat(filepath.Dir(name))
+	be.Err(t, err, os.ErrNotExist)
+}
@@ -213,11 +213,16 @@ func Move(db *bolt.DB, c *dupe.Config, assumeYes bool, src, dest string) error {
 			color.Secondary.Sprint("Bucket name:"), color.Debug.Sprint(bucket),
 			"New name:", color.Debug.Sprint(target))
 		printl(w, "Renames the database bucket, but this does not make changes to the file system.")
-		if !printer.AskYN("Rename bucket", assumeYes, printer.No) {
+		if !c.DryRun && !printer.AskYN("Rename bucket", assumeYes, printer.No) {
 			return nil
 		}
 	}
-	if err := database.Rename(db, bucket, target); err != nil {
+	if c.DryRun {
+		err = dryMove(db, c.Quiet, bucket, target)
+	} else {
+		err = database.Rename(db, bucket, target)
+	}
+	if err != nil {
 		if errors.Is(err, database.ErrSameName) {
 			printl(os.Stderr, "\nCannot move the bucket to the same directory as its current directory.")
 			printer.Example(fmt.Sprintf("dupers mv %s <new directory>\n", src))
@@ -227,8 +232,37 @@ func Move(db *bolt.DB, c *dupe.Config, assumeYes bool, src, dest string) error {
 	return nil
 }
 
+// dryMove checks that the bucket could be renamed to the target and prints the number of items to move,
+// without making any changes.
+func dryMove(db *bolt.DB, quiet bool, bucket, target string) error {
+	if bucket == target {
+		return database.ErrSameName
+	}
+	if err := database.Exist(db, target); err == nil {
+		return fmt.Errorf("%w: %s", bberr.ErrBucketExists, target)
+	}
+	items, err := database.Count(db, bucket)
+	if err != nil {
+		return err
+	}
+	p := message.NewPrinter(language.English)
+	s := p.Sprintf("Dry run, %d items would be moved to the renamed bucket: '%s'\n", items, target)
+	printer.Quiet(quiet, s)
+	return nil
+}
+
 // Remove the bucket from the database.
 func Remove(db *bolt.DB, quiet, assumeYes bool, args [2]string) error {
+	return remove(db, quiet, assumeYes, false, args)
+}
+
+// DryRemove prints the bucket and the number of items that Remove would delete from the database,
+// without making any changes.
+func DryRemove(db *bolt.DB, quiet bool, args [2]string) error {
+	return remove(db, quiet, true, true, args)
+}
+
+func remove(db *bolt.DB, quiet, assumeYes, dryRun bool, args [2]string) error {
 	if db == nil {
 		return bberr.ErrBucketNotFound
 	}
@@ -253,10 +287,15 @@ func Remove(db *bolt.DB, quiet, assumeYes bool, args [2]string) error {
 		printf(w, "%s\t%s\n", color.Secondary.Sprint("Bucket:"), color.Debug.Sprint(name))
 		p := message.NewPrinter(language.English)
 		printf(w, "%s\t%s\n", color.Secondary.Sprint("Items:"), color.Debug.Sprint(p.Sprint(items)))
-		if !printer.AskYN("Remove this bucket from the database", assumeYes, printer.No) {
+		if !dryRun && !printer.AskYN("Remove this bucket from the database", assumeYes, printer.No) {
 			return nil
 		}
 	}
+	if dryRun {
+		s := fmt.Sprintf("Dry run, the bucket would be removed from the database: '%s'\n", name)
+		printer.Quiet(quiet, s)
+		return nil
+	}
 	if err := removeBucket(db, name, bucket); err != nil {
 		return err
 	}
@@ -301,6 +340,7 @@ func bucketNotFound(db *bolt.DB, name string, err error) error {
 }
 
 // Rescan the bucket for changes with the file system.
+// The content of the archives is also scanned when archives is true or the bucket is set to use archives.
 func Rescan(db *bolt.DB, c *dupe.Config, archives bool, args [2]string) error {
 	if db == nil {
 		return bberr.ErrDatabaseNotOpen
@@ -325,6 +365,11 @@ func Rescan(db *bolt.DB, c *dupe.Config, archives bool, args [2]string) error {
 	} else if err := c.WalkDir(db, bucket); err != nil {
 		return err
 	}
+	if !archives && c.Option(path).Archives {
+		if err := c.WalkArchiver(db, bucket); err != nil {
+			return err
+		}
+	}
 	if !c.Quiet {
 		printl(os.Stdout, c.Status())
 	}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/bengarrett/dupers/internal/mock"
 	"github.com/bengarrett/dupers/pkg/cmd/task/bucket"
+	"github.com/bengarrett/dupers/pkg/database"
 	"github.com/bengarrett/dupers/pkg/dupe"
 	"github.com/nalgeon/be"
 )
@@ -97,6 +98,19 @@ func TestRemove(t *testing.T) {
 	be.Err(t, err)
 }
 
+func TestDryRemove(t *testing.T) {
+	db, path := mock.Database(t)
+	defer db.Close()
+	defer os.Remove(path)
+	bucket1, err := mock.Bucket(t, 1)
+	be.Err(t, err, nil)
+	args := [2]string{"", bucket1}
+	err = bucket.DryRemove(db, true, args)
+	be.Err(t, err, nil)
+	err = database.Exist(db, bucket1)
+	be.Err(t, err, nil)
+}
+
 func TestRescan(t *testing.T) {
 	args := [2]string{"", ""}
 	err := bucket.Rescan(nil, nil, false, args)
@@ -38,7 +38,11 @@ func Cleanup(c *dupe.Config, f *cmd.Flags) error {
 	if c == nil {
 		return dupe.ErrNilConfig
 	}
-	w := os.Stdout
+	var w io.Writer = os.Stdout
+	if f != nil && f.Format != nil && *f.Format != "" {
+		// the feedback would corrupt the machine-readable results
+		w = io.Discard
+	}
 	switch {
 	case f == nil:
 		return cmd.ErrNilFlag
@@ -50,15 +54,39 @@ func Cleanup(c *dupe.Config, f *cmd.Flags) error {
 		return fmt.Errorf("%w: rmplus", cmd.ErrNilFlag)
 	case f.Yes == nil:
 		return fmt.Errorf("%w: yes", cmd.ErrNilFlag)
+	}
+	var err error
+	run := true
+	switch {
 	case *f.Rm:
-		return runRemove(w, c)
+		err = runRemove(w, c)
 	case *f.RmPlus:
-		return runRemovePlus(w, c)
+		err = runRemovePlus(w, c)
 	case *f.Sensen:
-		return runSensen(w, c)
+		err = runSensen(w, c)
+	case f.Link != nil && *f.Link != "":
+		err = runLink(w, c, *f.Link)
+	case f.Reflink != nil && *f.Reflink:
+		err = runReflink(w, c)
 	default:
-		return nil
+		run = false
+	}
+	if s := c.DryRunStatus(); run && s != "" {
+		printl(w, s)
 	}
+	if manifest := c.Quarantined(); manifest != "" {
+		quarantined(w, manifest)
+	}
+	return err
+}
+
+// quarantined prints the location of the quarantine manifest and the command to restore the moved files.
+func quarantined(w io.Writer, manifest string) {
+	printl(w)
+	printl(w, color.Secondary.Sprint("The quarantine manifest is saved to: ")+manifest)
+	printl(w, "To put the files back, run:")
+	printl(w, color.Debug.Sprintf("dupers restore %q", manifest))
+	printl(w)
 }
 
 // runRemove deletes duplicate files.
@@ -72,6 +100,25 @@ func runRemove(w io.Writer, c *dupe.Config) error {
 	return nil
 }
 
+// runLink replaces duplicate files with links.
+// This is intended for the -link flag.
+func runLink(w io.Writer, c *dupe.Config, kind string) error {
+	s, err := c.Link(kind)
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprint(w, s)
+	return nil
+}
+
+// runReflink shares the data extents of duplicate files.
+// This is intended for the -reflink flag.
+func runReflink(w io.Writer, c *dupe.Config) error {
+	s, err := c.Reflink()
+	_, _ = fmt.Fprint(w, s)
+	return err
+}
+
 // runRemovePlus deletes duplicate files and empty directories.
 // This is intended for the -rm+ flag.
 func runRemovePlus(w io.Writer, c *dupe.Config) error {
@@ -159,10 +206,15 @@ func WalkScanSave(db *bolt.DB, c *dupe.Config, f *cmd.Flags) error {
 	for _, b := range c.Buckets {
 		buckets = append(buckets, string(b))
 	}
+	buckets = c.Writable(buckets...)
 
 	if !*f.Lookup && len(buckets) > 0 {
 		c.Debugger("non-fast mode, database cleanup.")
-		if err := database.Clean(db, c.Quiet, c.Debug, buckets...); err != nil {
+		clean := database.Clean
+		if c.DryRun {
+			clean = database.DryClean
+		}
+		if err := clean(db, c.Quiet, c.Debug, buckets...); err != nil {
 			printer.StderrCR(err)
 		}
 	}
@@ -196,7 +248,11 @@ func normalise(db *bolt.DB, c *dupe.Config) error {
 				question := "Scan and add this new directory to the database:\n" +
 					color.Warn.Sprintf(" %s\n\n", abs) +
 					"This could take awhile with large numbers of files"
-				if printer.AskYN(question, c.Yes, printer.Yes) {
+				var w io.Writer = os.Stdout
+				if c.Quiet {
+					w = os.Stderr
+				}
+				if printer.AskYNTo(w, question, c.Yes, printer.Yes) {
 					// continue automatically adds the bucket to the database
 					continue
 				}
@@ -11,6 +11,7 @@ import (
 	"text/tabwriter"
 
 	"github.com/bengarrett/dupers/pkg/cmd"
+	"github.com/bengarrett/dupers/pkg/cmd/config"
 	"github.com/gookit/color"
 )
 
@@ -45,6 +46,15 @@ func Debug(a *cmd.Aliases, f *cmd.Flags) (string, error) {
 	printf(w, "-quiet:\t\t%v\t\t%v\n", *f.Quiet, *a.Quiet)
 	printf(w, "-debug:\t\t%v\t\t%v\n", *f.Debug, *a.Debug)
 	printf(w, "-yes:\t\t%v\t\t%v\n", *f.Yes, *a.Yes)
+	if f.DB != nil {
+		printf(w, "-db:\t\t%q\t\t%v\n", *f.DB, na)
+	}
+	if f.Catalog != nil {
+		printf(w, "-catalog:\t\t%q\t\t%v\n", *f.Catalog, na)
+	}
+	if f.DryRun != nil {
+		printf(w, "-dry-run:\t\t%v\t\t%v\n", *f.DryRun, na)
+	}
 	printf(w, "-version:\t\t%v\t\t%v\n", *f.Version, *a.Version)
 	printf(w, "-help:\t\t%v\t\t%v\n", *f.Help, *a.Help)
 	printf(w, "-exact:\t\t%v\t\t%v\n", *f.Exact, *a.Exact)
@@ -53,12 +63,123 @@ func Debug(a *cmd.Aliases, f *cmd.Flags) (string, error) {
 	printf(w, "-delete:\t\t%v\t\t%v\n", *f.Rm, na)
 	printf(w, "-delete+:\t\t%v\t\t%v\n", *f.RmPlus, na)
 	printf(w, "-sensen:\t\t%v\t\t%v\n", *f.RmPlus, na)
+	if f.Self != nil {
+		printf(w, "-self:\t\t%v\t\t%v\n", *f.Self, na)
+	}
+	if f.Dirs != nil {
+		printf(w, "-dirs:\t\t%v\t\t%v\n", *f.Dirs, na)
+	}
+	if f.MinSize != nil {
+		printf(w, "-min-size:\t\t%q\t\t%v\n", f.MinSize.String(), na)
+	}
+	if f.MaxSize != nil {
+		printf(w, "-max-size:\t\t%q\t\t%v\n", f.MaxSize.String(), na)
+	}
+	if f.Empty != nil {
+		printf(w, "-empty:\t\t%v\t\t%v\n", *f.Empty, na)
+	}
+	if f.Include != nil {
+		printf(w, "-include:\t\t%q\t\t%v\n", *f.Include, na)
+	}
+	if f.Exclude != nil {
+		printf(w, "-exclude:\t\t%q\t\t%v\n", *f.Exclude, na)
+	}
+	if f.Keep != nil {
+		printf(w, "-keep:\t\t%q\t\t%v\n", *f.Keep, na)
+	}
+	if f.Link != nil {
+		printf(w, "-link:\t\t%q\t\t%v\n", *f.Link, na)
+	}
+	if f.Reflink != nil {
+		printf(w, "-reflink:\t\t%v\t\t%v\n", *f.Reflink, na)
+	}
+	if f.Prune != nil {
+		printf(w, "-prune:\t\t%v\t\t%v\n", *f.Prune, na)
+	}
+	if f.Quarantine != nil {
+		printf(w, "-quarantine:\t\t%q\t\t%v\n", *f.Quarantine, na)
+	}
+	if f.Trash != nil {
+		printf(w, "-trash:\t\t%v\t\t%v\n", *f.Trash, na)
+	}
+	if f.Format != nil {
+		printf(w, "-format:\t\t%q\t\t%v\n", *f.Format, na)
+	}
+	if f.Regex != nil {
+		printf(w, "-regex:\t\t%v\t\t%v\n", *f.Regex, na)
+	}
+	if f.Glob != nil {
+		printf(w, "-glob:\t\t%v\t\t%v\n", *f.Glob, na)
+	}
+	if f.Sum != nil {
+		printf(w, "-sum:\t\t%v\t\t%v\n", *f.Sum, na)
+	}
+	if f.Like != nil {
+		printf(w, "-like:\t\t%v\t\t%v\n", *f.Like, na)
+	}
+	if f.Fuzzy != nil {
+		printf(w, "-fuzzy:\t\t%v\t\t%v\n", *f.Fuzzy, na)
+	}
+	if f.Top != nil {
+		printf(w, "-top:\t\t%v\t\t%v\n", *f.Top, na)
+	}
+	if f.Ext != nil {
+		printf(w, "-ext:\t\t%q\t\t%v\n", *f.Ext, na)
+	}
+	if f.Type != nil {
+		printf(w, "-type:\t\t%q\t\t%v\n", *f.Type, na)
+	}
+	if f.Larger != nil {
+		printf(w, "-larger:\t\t%q\t\t%v\n", f.Larger.String(), na)
+	}
+	if f.Smaller != nil {
+		printf(w, "-smaller:\t\t%q\t\t%v\n", f.Smaller.String(), na)
+	}
+	if f.Newer != nil {
+		printf(w, "-newer:\t\t%q\t\t%v\n", f.Newer.String(), na)
+	}
+	if f.Older != nil {
+		printf(w, "-older:\t\t%q\t\t%v\n", f.Older.String(), na)
+	}
+	if f.Workers != nil {
+		printf(w, "-workers:\t\t%v\t\t%v\n", *f.Workers, na)
+	}
 	if err := w.Flush(); err != nil {
 		return "", err
 	}
 	return buf.String(), nil
 }
 
+// DebugConfig returns the options and the bucket settings of the configuration file.
+func DebugConfig(file *config.File) string {
+	buf := new(bytes.Buffer)
+	printl(buf)
+	if file == nil || file.Path == "" {
+		printl(buf, "Dupers configuration file: none")
+		return buf.String()
+	}
+	printf(buf, "Dupers configuration file: %s\n", file.Path)
+	for _, key := range file.Keys() {
+		val := file.Values[key]
+		if len(val) == 1 {
+			printf(buf, "  %s = %s\n", key, val[0])
+			continue
+		}
+		printf(buf, "  %s = %q\n", key, val)
+	}
+	dirs := make([]string, 0, len(file.Buckets))
+	for dir := range file.Buckets {
+		dirs = append(dirs, dir)
+	}
+	slices.Sort(dirs)
+	for _, dir := range dirs {
+		opts := file.Buckets[dir]
+		printf(buf, "  bucket %s: %s = %v, %s = %v\n", dir,
+			config.ReadOnly, opts.ReadOnly, config.Archives, opts.Archives)
+	}
+	return buf.String()
+}
+
 // DatabaseHelp creates the database command help.
 func DatabaseHelp(w io.Writer) {
 	printl(w)
@@ -69,7 +190,10 @@ func DatabaseHelp(w io.Writer) {
 	printf(w, "    dupers %s\t%s\n", Database_, "display statistics and bucket information")
 	printf(w, "    dupers %s\t%s\n", Backup_, "make a copy of the database")
 	printf(w, "    dupers %s\t%s\n", Clean_, "compact and remove items pointing to missing files")
+	printf(w, "    dupers -%s %s\t%s\n", cmd.Prune_, Clean_, "also remove the items skipped by the .dupersignore files")
 	printf(w, "    dupers %s <bucket>\t%s\n", LS_, "list the hashes and files in the bucket")
+	printf(w, "    dupers %s [buckets]\t%s\n", Report_, "list the duplicate files and the reclaimable space")
+	printf(w, "    dupers %s [buckets]\t%s\n", Rebuild_, "rebuild the filename search index of the buckets")
 	printf(w, "    dupers %s <bucket>\t%s\n", Up_, "add or update the bucket to the database")
 	printf(w, "    dupers %s <bucket>\t%s\n", UpPlus_, color.Danger.Sprint("(SLOW) add bucket using archives scan"))
 	printf(w, "    dupers %s <bucket>\t%s\n", RM_, "remove the bucket from the database")
@@ -87,6 +211,8 @@ func DupeHelp(w io.Writer) {
 	printl(w)
 	printl(w, "  Usage:")
 	printl(w, "    dupers [options] dupe <directory or file to check> [buckets to lookup]")
+	printf(w, "    dupers %s <manifest>\t%s\n", Restore_, "put back the files moved by the quarantine option")
+	printf(w, "    dupers %s [journal]\t%s\n", Undo_, "recreate the removed files from their surviving duplicates")
 	printl(w)
 	printl(w, "  Options:")
 	if flag.Lookup(cmd.Fast_) != nil {
@@ -95,6 +221,38 @@ func DupeHelp(w io.Writer) {
 		if len(f.Name) > 1 {
 			printf(w, "    -%s, -%s\t%s\n", f.Name[:1], f.Name, f.Usage)
 		}
+		f = flag.Lookup(cmd.Self_)
+		if f != nil {
+			printf(w, "        -%s\t%s\n", f.Name, f.Usage)
+		}
+		f = flag.Lookup(cmd.Dirs_)
+		if f != nil {
+			printf(w, "        -%s\t%s\n", f.Name, f.Usage)
+		}
+		f = flag.Lookup(cmd.MinSize_)
+		if f != nil {
+			printf(w, "        -%s=<size>\t%s\n", f.Name, f.Usage)
+		}
+		f = flag.Lookup(cmd.MaxSize_)
+		if f != nil {
+			printf(w, "        -%s=<size>\t%s\n", f.Name, f.Usage)
+		}
+		f = flag.Lookup(cmd.Empty_)
+		if f != nil {
+			printf(w, "        -%s\t%s\n", f.Name, f.Usage)
+		}
+		f = flag.Lookup(cmd.Include_)
+		if f != nil {
+			printf(w, "        -%s=<glob>\t%s\n", f.Name, f.Usage)
+		}
+		f = flag.Lookup(cmd.Exclude_)
+		if f != nil {
+			printf(w, "        -%s=<glob>\t%s\n", f.Name, f.Usage)
+		}
+		f = flag.Lookup(cmd.Format_)
+		if f != nil {
+			printf(w, "        -%s=json\t%s\n", f.Name, f.Usage)
+		}
 		f = flag.Lookup(cmd.Delete_)
 		if f != nil {
 			printf(w, "        -%s\t%s ", f.Name, color.Danger.Sprint(danger))
@@ -110,6 +268,30 @@ func DupeHelp(w io.Writer) {
 			printf(w, "        -%s\t%s ", f.Name, color.Danger.Sprint(danger))
 			printl(w, f.Usage)
 		}
+		f = flag.Lookup(cmd.Link_)
+		if f != nil {
+			printf(w, "        -%s=hard\t%s\n", f.Name, f.Usage)
+		}
+		f = flag.Lookup(cmd.Reflink_)
+		if f != nil {
+			printf(w, "        -%s\t%s\n", f.Name, f.Usage)
+		}
+		f = flag.Lookup(cmd.Quarantine_)
+		if f != nil {
+			printf(w, "        -%s=<dir>\t%s\n", f.Name, f.Usage)
+		}
+		f = flag.Lookup(cmd.Trash_)
+		if f != nil {
+			printf(w, "        -%s\t%s\n", f.Name, f.Usage)
+		}
+		f = flag.Lookup(cmd.Keep_)
+		if f != nil {
+			printf(w, "        -%s=oldest\t%s\n", f.Name, f.Usage)
+		}
+		f = flag.Lookup(cmd.Workers_)
+		if f != nil {
+			printf(w, "        -%s=%s\t%s\n", f.Name, f.DefValue, f.Usage)
+		}
 	}
 	DupeExample(w)
 }
@@ -152,6 +334,14 @@ func DupeExample(w io.Writer) {
 	printl(w, color.Secondary.Sprint(b))
 	printl(w, color.Info.Sprintf("     dupers dupe '%s' '%s'",
 		filepath.Join(cmd.Home(), "Documents"), "/var/www"))
+	const c = "  3. Find identical files within Pictures"
+	printl(w, color.Secondary.Sprint(c))
+	printl(w, color.Info.Sprintf("     dupers -self dupe '%s'",
+		filepath.Join(cmd.Home(), "Pictures")))
+	const d = "  4. Delete every duplicate of the files in Music except for the oldest copy"
+	printl(w, color.Secondary.Sprint(d))
+	printl(w, color.Info.Sprintf("     dupers -delete -keep=oldest dupe '%s'",
+		filepath.Join(cmd.Home(), "Music")))
 }
 
 func dupeWindows(w io.Writer) {
@@ -161,6 +351,14 @@ func dupeWindows(w io.Writer) {
 	printl(w, color.Secondary.Sprint(a))
 	printl(w, color.Info.Sprintf("    dupers dupe \"%s\" %s %s",
 		filepath.Join(cmd.Home(), "Documents"), "D:", "E:"))
+	const b = "    # find identical files within Pictures"
+	printl(w, color.Secondary.Sprint(b))
+	printl(w, color.Info.Sprintf("    dupers -self dupe \"%s\"",
+		filepath.Join(cmd.Home(), "Pictures")))
+	const c = "    # delete every duplicate of the files in Music except for the oldest copy"
+	printl(w, color.Secondary.Sprint(c))
+	printl(w, color.Info.Sprintf("    dupers -delete -keep=oldest dupe \"%s\"",
+		filepath.Join(cmd.Home(), "Music")))
 }
 
 func ProgramOpts(w io.Writer) {
@@ -179,6 +377,15 @@ func ProgramOpts(w io.Writer) {
 			}
 			printf(w, "    -%v, -%v\t%v\n", f.Name[:1], f.Name, f.Usage)
 		}
+		if f = flag.Lookup(cmd.DryRun_); f != nil {
+			printf(w, "        -%v\t%v\n", f.Name, f.Usage)
+		}
+		if f = flag.Lookup(cmd.DB_); f != nil {
+			printf(w, "        -%v=<file>\t%v\n", f.Name, f.Usage)
+		}
+		if f = flag.Lookup(cmd.Catalog_); f != nil {
+			printf(w, "        -%v=<name>\t%v\n", f.Name, f.Usage)
+		}
 	}
 	printf(w, "    -h, %s\tshow this list of options\n", "-help")
 }
@@ -189,6 +396,8 @@ func SearchHelp(w io.Writer) {
 	printl(w, color.Primary.Sprint("SEARCH command:"))
 	printl(w, "  Lookup a file or a directory name in the database.")
 	printl(w, "  The <search expression> can be a partial or complete, file or directory name.")
+	printl(w, "  With the sum or like options, it is a SHA-256 checksum or the path of a local file.")
+	printl(w, "  With the filter options, an empty \"\" expression searches every stored file.")
 	printl(w)
 	printl(w, "  Usage:")
 	printl(w, "    dupers [options] search <search expression> [optional, buckets to search]")
@@ -202,6 +411,60 @@ func SearchHelp(w io.Writer) {
 		if f != nil {
 			printf(w, "    -%v, -%v\t\t%v\n", f.Name[:1], f.Name, f.Usage)
 		}
+		f = flag.Lookup(cmd.Regex_)
+		if f != nil {
+			printf(w, "        -%v\t\t%v\n", f.Name, f.Usage)
+		}
+		f = flag.Lookup(cmd.Glob_)
+		if f != nil {
+			printf(w, "        -%v\t\t%v\n", f.Name, f.Usage)
+		}
+		f = flag.Lookup(cmd.Sum_)
+		if f != nil {
+			printf(w, "        -%v\t\t%v\n", f.Name, f.Usage)
+		}
+		f = flag.Lookup(cmd.Like_)
+		if f != nil {
+			printf(w, "        -%v\t\t%v\n", f.Name, f.Usage)
+		}
+		f = flag.Lookup(cmd.Fuzzy_)
+		if f != nil {
+			printf(w, "        -%v\t\t%v\n", f.Name, f.Usage)
+		}
+		f = flag.Lookup(cmd.Top_)
+		if f != nil {
+			printf(w, "        -%v=<n>\t\t%v\n", f.Name, f.Usage)
+		}
+		f = flag.Lookup(cmd.Ext_)
+		if f != nil {
+			printf(w, "        -%v=<list>\t\t%v\n", f.Name, f.Usage)
+		}
+		f = flag.Lookup(cmd.Type_)
+		if f != nil {
+			printf(w, "        -%v=<type>\t\t%v\n", f.Name, f.Usage)
+		}
+		for _, name := range []string{cmd.Larger_, cmd.Smaller_} {
+			if f = flag.Lookup(name); f != nil {
+				printf(w, "        -%v=<size>\t\t%v\n", f.Name, f.Usage)
+			}
+		}
+		for _, name := range []string{cmd.Newer_, cmd.Older_} {
+			if f = flag.Lookup(name); f != nil {
+				printf(w, "        -%v=<date>\t\t%v\n", f.Name, f.Usage)
+			}
+		}
+		f = flag.Lookup(cmd.Format_)
+		if f != nil {
+			printf(w, "        -%v=json\t\t%v\n", f.Name, f.Usage)
+		}
+		f = flag.Lookup(cmd.Include_)
+		if f != nil {
+			printf(w, "        -%v=<glob>\t\t%v\n", f.Name, f.Usage)
+		}
+		f = flag.Lookup(cmd.Exclude_)
+		if f != nil {
+			printf(w, "        -%v=<glob>\t\t%v\n", f.Name, f.Usage)
+		}
 	}
 	SearchExample(w)
 }
@@ -215,11 +478,29 @@ func SearchExample(w io.Writer) {
 		printr(w, pad4+color.Info.Sprintf(" dupers search \"foo\" \"%s\"", cmd.Home()))
 		printr(w, color.Secondary.Sprint("\n  2. Search for filenames containing .zip\n"))
 		printr(w, pad4+color.Info.Sprint(" dupers -name search \".zip\""))
+		printr(w, color.Secondary.Sprint("\n  3. Search for the JPEG photos taken in 2019\n"))
+		printr(w, pad4+color.Info.Sprint(" dupers -regex search \"IMG_2019\\d{4}\\.jpe?g$\""))
+		printr(w, color.Secondary.Sprint("\n  4. Search for the copies of a local file\n"))
+		printr(w, pad4+color.Info.Sprint(" dupers -like search \"report.pdf\""))
+		printr(w, color.Secondary.Sprint("\n  5. Search for the videos over 1 GB modified before 2015\n"))
+		printr(w, pad4+color.Info.Sprint(" dupers -type=video -larger=1GB -older=2015 search \"\""))
+		printr(w, color.Secondary.Sprint("\n  6. Search for the closest filenames to a misspelled receipt\n"))
+		printr(w, pad4+color.Info.Sprint(" dupers -fuzzy -name search \"recipt\""))
 		printl(w)
 		return
 	}
 	printr(w, pad4+color.Info.Sprintf(" dupers search 'foo' '%s'", cmd.Home()))
 	printr(w, color.Secondary.Sprint("\n  2. Search for filenames containing .zip\n"))
 	printr(w, pad4+color.Info.Sprint(" dupers -name search '.zip'"))
+	printr(w, color.Secondary.Sprint("\n  3. Search for the JPEG photos taken in 2019\n"))
+	printr(w, pad4+color.Info.Sprint(" dupers -regex search 'IMG_2019\\d{4}\\.jpe?g$'"))
+	printr(w, color.Secondary.Sprint("\n  4. Search for the PNG images within any photos directory\n"))
+	printr(w, pad4+color.Info.Sprint(" dupers -glob search 'photos/**/*.png'"))
+	printr(w, color.Secondary.Sprint("\n  5. Search for the copies of a local file\n"))
+	printr(w, pad4+color.Info.Sprint(" dupers -like search 'report.pdf'"))
+	printr(w, color.Secondary.Sprint("\n  6. Search for the videos over 1 GB modified before 2015\n"))
+	printr(w, pad4+color.Info.Sprint(" dupers -type=video -larger=1GB -older=2015 search ''"))
+	printr(w, color.Secondary.Sprint("\n  7. Search for the closest filenames to a misspelled receipt\n"))
+	printr(w, pad4+color.Info.Sprint(" dupers -fuzzy -name search 'recipt'"))
 	printl(w)
 }
@@ -9,18 +9,24 @@ import (
 	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/bengarrett/dupers/internal/printer"
 	"github.com/bengarrett/dupers/pkg/cmd"
 	"github.com/bengarrett/dupers/pkg/database"
+	"github.com/bengarrett/dupers/pkg/database/csv"
+	"github.com/bengarrett/dupers/pkg/dupe/ignore"
+	"github.com/bengarrett/dupers/pkg/dupe/parse"
 	bolt "go.etcd.io/bbolt"
 	bberr "go.etcd.io/bbolt/errors"
 )
 
 var (
+	ErrMode    = errors.New("only one of the regex, glob, sum, like or fuzzy options can be used")
 	ErrNoArgs  = errors.New("request is missing arguments")
 	ErrNoFlags = errors.New("no command flags provided")
 	ErrSearch  = errors.New("search request needs an expression")
+	ErrSum     = errors.New("search term is not a valid SHA-256 checksum")
 )
 
 func printl(w io.Writer, a ...any) {
@@ -44,7 +50,110 @@ func CmdErr(args int, test bool) error {
 	return ErrSearch
 }
 
-func Compare(db *bolt.DB, f *cmd.Flags, term string, buckets []string) (*database.Matches, error) { //nolint:cyclop
+// Pattern returns the compiled search term of the regex or glob options,
+// or nil when neither option is used.
+func Pattern(f *cmd.Flags, term string) (*database.Pattern, error) {
+	if f == nil {
+		return nil, ErrNoFlags
+	}
+	regex, glob := on(f.Regex), on(f.Glob)
+	exact, base := on(f.Exact), on(f.Filename)
+	modes := 0
+	for _, mode := range []bool{regex, glob, on(f.Sum), on(f.Like), on(f.Fuzzy)} {
+		if mode {
+			modes++
+		}
+	}
+	switch {
+	case modes > 1:
+		return nil, ErrMode
+	case regex:
+		return database.Regexp(term, exact, base)
+	case glob:
+		return database.Glob(term, exact, base)
+	}
+	return nil, nil
+}
+
+// Content returns true when the search term is a checksum or a local file,
+// which is matched with the stored checksums instead of the filenames and paths.
+func Content(f *cmd.Flags) bool {
+	return f != nil && (on(f.Sum) || on(f.Like))
+}
+
+// Checksum returns the SHA-256 checksum of the search term.
+// The sum option uses the term as a hexadecimal checksum,
+// and the like option reads the content of the named local file.
+func Checksum(f *cmd.Flags, term string) ([32]byte, error) {
+	if f == nil {
+		return [32]byte{}, ErrNoFlags
+	}
+	if on(f.Like) {
+		return parse.Read(term)
+	}
+	sum, err := csv.Checksum(strings.ToLower(strings.TrimSpace(term)))
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("%w: %w", ErrSum, err)
+	}
+	return sum, nil
+}
+
+// Filters returns the search filters of the flags, or nil when no filter is used.
+func Filters(f *cmd.Flags) (*database.Filter, error) {
+	if f == nil {
+		return nil, ErrNoFlags
+	}
+	x := &database.Filter{}
+	if f.Ext != nil {
+		for ext := range strings.SplitSeq(*f.Ext, ",") {
+			if ext = strings.TrimSpace(ext); ext != "" {
+				x.Exts = append(x.Exts, ext)
+			}
+		}
+	}
+	if f.Type != nil {
+		x.Kind = strings.TrimSpace(*f.Type)
+	}
+	if f.Larger != nil {
+		x.Larger = int64(*f.Larger)
+	}
+	if f.Smaller != nil {
+		x.Smaller = int64(*f.Smaller)
+	}
+	if f.Newer != nil {
+		x.Newer = time.Time(*f.Newer)
+	}
+	if f.Older != nil {
+		x.Older = time.Time(*f.Older)
+	}
+	if x.Empty() {
+		return nil, nil
+	}
+	if err := x.Check(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// on returns true when the boolean option is used.
+func on(b *bool) bool {
+	return b != nil && *b
+}
+
+// Compare the term with the stored filenames and paths, using the search options of the flags.
+func Compare(db *bolt.DB, f *cmd.Flags, term string, buckets []string) (*database.Matches, error) {
+	p, err := Pattern(f, term)
+	if err != nil {
+		return nil, err
+	}
+	return ComparePattern(db, f, p, term, buckets)
+}
+
+// ComparePattern compares the pattern with the stored filenames and paths.
+// When the pattern is nil, the stored filenames and paths containing the term are used,
+// or with the sum or like options, the stored checksums that match the term.
+// The matches are then narrowed by the search filters, which use every stored file when the term is empty.
+func ComparePattern(db *bolt.DB, f *cmd.Flags, p *database.Pattern, term string, buckets []string) (*database.Matches, error) { //nolint:cyclop,lll
 	if db == nil {
 		return nil, bberr.ErrDatabaseNotOpen
 	}
@@ -54,9 +163,34 @@ func Compare(db *bolt.DB, f *cmd.Flags, term string, buckets []string) (*databas
 	if f.Filename == nil || f.Exact == nil {
 		return nil, ErrNoFlags
 	}
-	var err error
+	flt, err := Filters(f)
+	if err != nil {
+		return nil, err
+	}
 	var m *database.Matches
 	switch {
+	case Content(f):
+		// the sum and like options always need a term, even when the filters are used
+		if term == "" {
+			return nil, database.ErrNoTerm
+		}
+		sum, errS := Checksum(f, term)
+		if errS != nil {
+			return nil, errS
+		}
+		if m, err = database.CompareSum(db, sum, buckets...); err != nil {
+			return nil, Error(err)
+		}
+	case term == "" && flt != nil:
+		if m, err = database.CompareFilter(db, flt, buckets...); err != nil {
+			return nil, Error(err)
+		}
+		// the matches have already been filtered
+		flt = nil
+	case p != nil:
+		if m, err = database.ComparePattern(db, p, buckets...); err != nil {
+			return nil, Error(err)
+		}
 	case *f.Filename && !*f.Exact:
 		if m, err = database.CompareBaseNoCase(db, term, buckets...); err != nil {
 			return nil, Error(err)
@@ -74,9 +208,69 @@ func Compare(db *bolt.DB, f *cmd.Flags, term string, buckets []string) (*databas
 			return nil, Error(err)
 		}
 	}
+	if m != nil {
+		m.Ignore(filter(f).Skip)
+		if err := m.Filter(db, flt); err != nil {
+			return nil, Error(err)
+		}
+	}
 	return m, nil
 }
 
+// Fuzzy ranks the stored filenames and paths by how closely they resemble the term,
+// which is useful for the misspelled or misremembered filenames.
+// The ranks are narrowed by the include and exclude globs and the search filters,
+// then only the closest matches are kept, using the number of the top option.
+func Fuzzy(db *bolt.DB, f *cmd.Flags, term string, buckets []string) (database.Ranks, error) {
+	if db == nil {
+		return nil, bberr.ErrDatabaseNotOpen
+	}
+	if f == nil || f.Filename == nil || f.Exact == nil {
+		return nil, ErrNoFlags
+	}
+	if _, err := Pattern(f, term); err != nil {
+		return nil, err
+	}
+	flt, err := Filters(f)
+	if err != nil {
+		return nil, err
+	}
+	ranks, err := database.CompareFuzzy(db, term, *f.Exact, *f.Filename, buckets...)
+	if err != nil {
+		return nil, Error(err)
+	}
+	m := ranks.Matches()
+	m.Ignore(filter(f).Skip)
+	if err := m.Filter(db, flt); err != nil {
+		return nil, Error(err)
+	}
+	ranks = ranks.Keep(m)
+	if n := top(f); len(ranks) > n {
+		ranks = ranks[:n]
+	}
+	return ranks, nil
+}
+
+// top returns the number of fuzzy search results to keep.
+func top(f *cmd.Flags) int {
+	if f.Top == nil || *f.Top < 1 {
+		return cmd.Top
+	}
+	return *f.Top
+}
+
+// filter returns the include and exclude globs of the flags.
+func filter(f *cmd.Flags) *ignore.Filter {
+	x := &ignore.Filter{}
+	if f.Include != nil {
+		x.Include = *f.Include
+	}
+	if f.Exclude != nil {
+		x.Exclude = *f.Exclude
+	}
+	return x
+}
+
 // Error parses the errors from search compares.
 func Error(err error) error {
 	if errors.Is(err, database.ErrEmpty) {
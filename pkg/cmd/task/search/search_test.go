@@ -2,7 +2,10 @@
 package search_test
 
 import (
+	"encoding/hex"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/bengarrett/dupers/internal/mock"
@@ -51,3 +54,142 @@ func TestCompare(t *testing.T) {
 	be.Err(t, err)
 	be.Equal(t, m, nil)
 }
+
+func TestPattern(t *testing.T) {
+	p, err := search.Pattern(nil, "")
+	be.Err(t, err, search.ErrNoFlags)
+	be.Equal(t, p, nil)
+	val, no := true, false
+	f := cmd.Flags{Regex: &no, Glob: &no}
+	p, err = search.Pattern(&f, "*.jpg")
+	be.Err(t, err, nil)
+	be.Equal(t, p, nil)
+	f = cmd.Flags{Regex: &val, Glob: &val}
+	_, err = search.Pattern(&f, "*.jpg")
+	be.Err(t, err, search.ErrMode)
+	f = cmd.Flags{Glob: &val}
+	p, err = search.Pattern(&f, "*.jpg")
+	be.Err(t, err, nil)
+	be.True(t, p.Match("/home/user/photo.JPG"))
+	f = cmd.Flags{Regex: &val}
+	_, err = search.Pattern(&f, "(")
+	be.Err(t, err, database.ErrRegexp)
+}
+
+func TestChecksum(t *testing.T) {
+	_, err := search.Checksum(nil, "")
+	be.Err(t, err, search.ErrNoFlags)
+	val := true
+	f := cmd.Flags{Sum: &val}
+	be.True(t, search.Content(&f))
+	sum1 := mock.ItemSum(t, 1)
+	sum, err := search.Checksum(&f, strings.ToUpper(sum1))
+	be.Err(t, err, nil)
+	be.Equal(t, hex.EncodeToString(sum[:]), sum1)
+	_, err = search.Checksum(&f, "abc")
+	be.Err(t, err, search.ErrSum)
+	_, err = search.Checksum(&f, strings.Repeat("x", 64))
+	be.Err(t, err, search.ErrSum)
+	f = cmd.Flags{Like: &val}
+	sum, err = search.Checksum(&f, mock.Item(t, 1))
+	be.Err(t, err, nil)
+	be.Equal(t, hex.EncodeToString(sum[:]), sum1)
+	_, err = search.Checksum(&f, "this-file-does-not-exist")
+	be.Err(t, err)
+	f = cmd.Flags{Sum: &val, Like: &val}
+	_, err = search.Pattern(&f, sum1)
+	be.Err(t, err, search.ErrMode)
+}
+
+func TestCompare_Content(t *testing.T) {
+	val, no := true, false
+	f := cmd.Flags{Filename: &no, Exact: &no, Like: &val}
+	db, path := mock.Database(t)
+	defer db.Close()
+	defer os.Remove(path)
+	item1 := mock.Item(t, 1)
+	m, err := search.Compare(db, &f, item1, nil)
+	be.Err(t, err, nil)
+	be.Equal(t, len(*m), 1)
+	_, ok := (*m)[database.Filepath(item1)]
+	be.True(t, ok)
+	f = cmd.Flags{Filename: &no, Exact: &no, Sum: &val}
+	m, err = search.Compare(db, &f, mock.ItemSum(t, 2), nil)
+	be.Err(t, err, nil)
+	be.Equal(t, len(*m), 1)
+}
+
+func TestFilters(t *testing.T) {
+	_, err := search.Filters(nil)
+	be.Err(t, err, search.ErrNoFlags)
+	empty, kind := "", "archive"
+	f := cmd.Flags{Ext: &empty}
+	x, err := search.Filters(&f)
+	be.Err(t, err, nil)
+	be.Equal(t, x, nil)
+	exts := " jpg, .PNG ,,"
+	f = cmd.Flags{Ext: &exts, Type: &kind}
+	x, err = search.Filters(&f)
+	be.Err(t, err, nil)
+	be.Equal(t, x.Exts, []string{"jpg", ".PNG"})
+	be.Equal(t, x.Kind, kind)
+	kind = "spreadsheet"
+	_, err = search.Filters(&f)
+	be.Err(t, err, database.ErrKind)
+}
+
+func TestCompare_Filters(t *testing.T) {
+	no := false
+	larger := cmd.Size(1)
+	f := cmd.Flags{Filename: &no, Exact: &no, Larger: &larger}
+	db, path := mock.Database(t)
+	defer db.Close()
+	defer os.Remove(path)
+	m, err := search.Compare(db, &f, "", nil)
+	be.Err(t, err, nil)
+	be.Equal(t, len(*m), 3)
+	item1 := mock.Item(t, 1)
+	m, err = search.Compare(db, &f, filepath.Base(item1), nil)
+	be.Err(t, err, nil)
+	be.Equal(t, len(*m), 1)
+	larger = cmd.Size(1 << 40)
+	m, err = search.Compare(db, &f, filepath.Base(item1), nil)
+	be.Err(t, err, nil)
+	be.Equal(t, len(*m), 0)
+	// the sum and like options need a term, even with the filters
+	val := true
+	larger = cmd.Size(1)
+	f.Sum = &val
+	_, err = search.Compare(db, &f, "", nil)
+	be.Err(t, err, database.ErrNoTerm)
+	f.Sum, f.Like = nil, &val
+	_, err = search.Compare(db, &f, "", nil)
+	be.Err(t, err, database.ErrNoTerm)
+}
+
+func TestFuzzy(t *testing.T) {
+	val, no := true, false
+	top := 1
+	f := cmd.Flags{Filename: &val, Exact: &no, Fuzzy: &val}
+	_, err := search.Fuzzy(nil, &f, "a", nil)
+	be.Err(t, err, errors.ErrDatabaseNotOpen)
+	db, path := mock.Database(t)
+	defer db.Close()
+	defer os.Remove(path)
+	_, err = search.Fuzzy(db, nil, "a", nil)
+	be.Err(t, err, search.ErrNoFlags)
+	r, err := search.Fuzzy(db, &f, "3a9dmxg", nil)
+	be.Err(t, err, nil)
+	be.Equal(t, len(r), 1)
+	be.Equal(t, r[0].Path, database.Filepath(mock.Item(t, 1)))
+	r, err = search.Fuzzy(db, &f, "a", nil)
+	be.Err(t, err, nil)
+	be.Equal(t, len(r), 2)
+	f.Top = &top
+	r, err = search.Fuzzy(db, &f, "a", nil)
+	be.Err(t, err, nil)
+	be.Equal(t, len(r), 1)
+	f.Regex = &val
+	_, err = search.Fuzzy(db, &f, "a", nil)
+	be.Err(t, err, search.ErrMode)
+}
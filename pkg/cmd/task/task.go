@@ -9,6 +9,7 @@ import (
 	"io"
 	"io/fs"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"text/tabwriter"
@@ -20,6 +21,9 @@ import (
 	"github.com/bengarrett/dupers/pkg/cmd/task/search"
 	"github.com/bengarrett/dupers/pkg/database"
 	"github.com/bengarrett/dupers/pkg/dupe"
+	"github.com/bengarrett/dupers/pkg/dupe/ignore"
+	"github.com/bengarrett/dupers/pkg/dupe/journal"
+	"github.com/bengarrett/dupers/pkg/dupe/quarantine"
 	"github.com/dustin/go-humanize"
 	"github.com/gookit/color"
 	bolt "go.etcd.io/bbolt"
@@ -29,13 +33,19 @@ import (
 )
 
 var (
-	ErrArgs      = errors.New("no buckets were given as arguments")
-	ErrEmptyDB   = errors.New("the database is empty with no buckets")
-	ErrToFewArgs = errors.New("too few arguments were given")
-	ErrCommand   = errors.New("command is unknown")
-	ErrNilFlags  = errors.New("flags cannot be a nil value")
-	ErrNoArgs    = errors.New("arguments cannot be empty")
-	ErrUserExit  = errors.New("cannot dupe check a directory that isn't stored as a bucket")
+	ErrArgs       = errors.New("no buckets were given as arguments")
+	ErrEmptyDB    = errors.New("the database is empty with no buckets")
+	ErrToFewArgs  = errors.New("too few arguments were given")
+	ErrCommand    = errors.New("command is unknown")
+	ErrNilFlags   = errors.New("flags cannot be a nil value")
+	ErrNoArgs     = errors.New("arguments cannot be empty")
+	ErrNoManifest = errors.New("restore requires the path of a quarantine manifest")
+	ErrNoJournal  = errors.New("there is no journal of removed files to undo")
+	ErrSelfRM     = errors.New("delete options cannot be used with the self flag, except for delete with a keep policy")
+	ErrSelfLink   = errors.New("the dirs, link and reflink options cannot be used with the self flag")
+	ErrLinkRM     = errors.New("the link options cannot be used together or with the delete, quarantine or trash options")
+	ErrDirs       = errors.New("the dirs option cannot be used with the delete, link or format options")
+	ErrUserExit   = errors.New("cannot dupe check a directory that isn't stored as a bucket")
 )
 
 const (
@@ -48,8 +58,12 @@ const (
 	Import_   = "import"
 	LS_       = "ls"
 	MV_       = "mv"
+	Rebuild_  = "rebuild"
+	Report_   = "report"
+	Restore_  = "restore"
 	RM_       = "rm"
 	Search_   = "search"
+	Undo_     = "undo"
 	Up_       = "up"
 	UpPlus_   = "up+"
 	winOS     = "windows"
@@ -120,19 +134,160 @@ func Database(db *bolt.DB, c *dupe.Config, args ...string) error { //nolint:cycl
 	case LS_:
 		return bucket.List(db, quiet, buckets)
 	case MV_:
+		if err := readOnly(c, buckets[1]); err != nil {
+			return err
+		}
 		return move(db, c, assumeYes, args...)
+	case Rebuild_:
+		return rebuild(db, quiet, args[1:]...)
+	case Report_:
+		return report(db, args[1:]...)
 	case RM_:
+		if err := readOnly(c, buckets[1]); err != nil {
+			return err
+		}
+		if c.DryRun {
+			return bucket.DryRemove(db, quiet, buckets)
+		}
 		return bucket.Remove(db, quiet, assumeYes, buckets)
-	case Up_:
-		return bucket.Rescan(db, c, false, buckets)
-	case UpPlus_:
-		return bucket.Rescan(db, c, true, buckets)
+	case Up_, UpPlus_:
+		if err := readOnly(c, buckets[1]); err != nil {
+			return err
+		}
+		return bucket.Rescan(db, c, selection == UpPlus_, buckets)
 	default:
 		return ErrCommand
 	}
 	return nil
 }
 
+// readOnly returns an error if the named bucket is set as read-only in the configuration file.
+func readOnly(c *dupe.Config, name string) error {
+	if name == "" {
+		return nil
+	}
+	abs, err := database.Abs(name)
+	if err != nil {
+		return err
+	}
+	return c.ReadOnly(abs)
+}
+
+// rebuild the search index of the named buckets or all the buckets.
+func rebuild(db *bolt.DB, quiet bool, buckets ...string) error {
+	if db == nil {
+		return bberr.ErrDatabaseNotOpen
+	}
+	names := make([]string, 0, len(buckets))
+	for _, name := range buckets {
+		abs, err := database.Abs(name)
+		if err != nil {
+			return err
+		}
+		names = append(names, abs)
+	}
+	n, err := database.Rebuild(db, names...)
+	if err != nil {
+		return err
+	}
+	p := message.NewPrinter(language.English)
+	printer.Quiet(quiet, color.Secondary.Sprint("Rebuilt the search index of ")+
+		color.Primary.Sprint(p.Sprintf("%d items", n)))
+	return nil
+}
+
+// report prints the duplicate groups stored in the named buckets or all the buckets.
+func report(db *bolt.DB, buckets ...string) error {
+	if db == nil {
+		return bberr.ErrDatabaseNotOpen
+	}
+	names := make([]string, 0, len(buckets))
+	for _, name := range buckets {
+		abs, err := database.Abs(name)
+		if err != nil {
+			return err
+		}
+		if err := database.Exist(db, abs); err != nil {
+			return fmt.Errorf("%w: %s", err, abs)
+		}
+		names = append(names, abs)
+	}
+	groups, err := database.Report(db, names...)
+	if err != nil {
+		return err
+	}
+	s, err := database.ReportInfo(groups...)
+	if err != nil {
+		return err
+	}
+	printr(os.Stdout, s)
+	return nil
+}
+
+// Restore parses the restore command and puts back the files listed in a quarantine manifest.
+// The argument can either be the manifest or the dated quarantine directory that contains it.
+func Restore(c *dupe.Config, args ...string) error {
+	if c == nil {
+		return dupe.ErrNilConfig
+	}
+	const manifest = 1
+	if len(args) <= manifest {
+		printer.StderrCR(ErrNoManifest)
+		printer.Example("\ndupers restore <manifest>")
+		return ErrNoManifest
+	}
+	name := args[manifest]
+	if info, err := os.Stat(name); err == nil && info.IsDir() {
+		name = filepath.Join(name, quarantine.Manifest)
+	}
+	c.Debugger("restore the quarantine manifest: " + name)
+	n, err := quarantine.Restore(os.Stdout, name)
+	if err != nil {
+		return err
+	}
+	if !c.Quiet {
+		p := message.NewPrinter(language.English)
+		printl(os.Stdout, color.Secondary.Sprint("Restored ")+
+			color.Primary.Sprint(p.Sprintf("%d files", n)))
+	}
+	return nil
+}
+
+// Undo parses the undo command and recreates the files listed in the journal of removed files.
+// The optional argument is the path of a journal, otherwise the journal next to the database is used.
+func Undo(c *dupe.Config, args ...string) error {
+	if c == nil {
+		return dupe.ErrNilConfig
+	}
+	const named = 1
+	name := ""
+	if len(args) > named {
+		name = args[named]
+	} else {
+		db, err := database.DB()
+		if err != nil {
+			return err
+		}
+		name = journal.Path(db)
+	}
+	if _, err := os.Stat(name); errors.Is(err, os.ErrNotExist) {
+		printer.StderrCR(fmt.Errorf("%w: %s", ErrNoJournal, name))
+		printer.Example("\ndupers undo [journal]")
+		return ErrNoJournal
+	}
+	c.Debugger("undo the journal: " + name)
+	n, err := journal.Undo(os.Stdout, name)
+	if err != nil {
+		return err
+	}
+	if !c.Quiet {
+		p := message.NewPrinter(language.English)
+		printl(os.Stdout, color.Secondary.Sprint("Recreated ")+
+			color.Primary.Sprint(p.Sprintf("%d files", n)))
+	}
+	return nil
+}
+
 func move(db *bolt.DB, c *dupe.Config, assumeYes bool, args ...string) error {
 	const src, dest = 1, 2
 	s, d := "", ""
@@ -145,6 +300,23 @@ func move(db *bolt.DB, c *dupe.Config, assumeYes bool, args ...string) error {
 	return bucket.Move(db, c, assumeYes, s, d)
 }
 
+// writable returns the buckets in the database that are not read-only.
+// It returns nil when no bucket is read-only, so every bucket can be used.
+func writable(db *bolt.DB, c *dupe.Config) ([]string, error) {
+	if len(c.Options) == 0 {
+		return nil, nil
+	}
+	all, err := database.All(db)
+	if err != nil {
+		return nil, err
+	}
+	names := c.Writable(all...)
+	if len(names) == len(all) {
+		return nil, nil
+	}
+	return names, nil
+}
+
 // Dupe parses the dupe command.
 func Dupe(db *bolt.DB, c *dupe.Config, f *cmd.Flags, args ...string) error {
 	if db == nil {
@@ -154,6 +326,13 @@ func Dupe(db *bolt.DB, c *dupe.Config, f *cmd.Flags, args ...string) error {
 		return ErrNilFlags
 	}
 	c.Debugger("dupe command: " + strings.Join(args, " "))
+	if c.Journal == "" {
+		c.Journal = journal.Path(db.Path())
+	}
+	// the format must quiet the bucket stats and prompts that are printed before the walk
+	if _, err := checkFormat(c, f); err != nil {
+		return err
+	}
 
 	// fetch bucket info
 	b, err := database.All(db)
@@ -193,6 +372,90 @@ func Dupe(db *bolt.DB, c *dupe.Config, f *cmd.Flags, args ...string) error {
 	return WalkScan(db, c, f, args...)
 }
 
+// DupeSelf parses the dupe command when used with the self flag.
+// It finds identical files within the source without opening the database or using any buckets.
+func DupeSelf(c *dupe.Config, f *cmd.Flags, args ...string) error {
+	if c == nil {
+		return dupe.ErrNilConfig
+	}
+	if f == nil || f.Rm == nil || f.RmPlus == nil || f.Sensen == nil {
+		return ErrNilFlags
+	}
+	if *f.RmPlus || *f.Sensen || *f.Rm && c.Keep == "" {
+		return ErrSelfRM
+	}
+	if link(f) != "" || f.Reflink != nil && *f.Reflink || dirs(f) {
+		return ErrSelfLink
+	}
+	format, err := checkFormat(c, f)
+	if err != nil {
+		return err
+	}
+	if err := checkKeep(c); err != nil {
+		return err
+	}
+	c.Debugger("dupe self command: " + strings.Join(args, " "))
+	// the self check does not use the database, so the journal path must not create it
+	if name, err := database.Location(); err == nil && c.Journal == "" && !c.Test {
+		c.Journal = journal.Path(name)
+	}
+	const source = 1
+	if len(args) <= source {
+		duplicate.Check(source+1, args...)
+		return ErrToFewArgs
+	}
+	if len(args) > source+1 {
+		c.Debugger("self check ignores the buckets: " + strings.Join(args[source+1:], " "))
+	}
+	path := args[source]
+	if err := c.SetSource(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			printer.StderrCR(os.ErrNotExist)
+			printf(os.Stdout, "File or directory path: %s\n", path)
+			printer.Example("\ndupers -self dupe <directory>")
+		}
+		return err
+	}
+	if err := c.CheckRemove(); err != nil {
+		return err
+	}
+	if err := c.WalkSource(); err != nil {
+		return err
+	}
+	groups, err := c.Self()
+	if err != nil {
+		return err
+	}
+	if format != "" {
+		results := dupe.GroupResults(groups...)
+		if *f.Rm {
+			if _, err := c.DelGroups(groups...); err != nil {
+				return err
+			}
+			c.SetActions(results...)
+		}
+		return dupe.WriteResults(os.Stdout, format, results...)
+	}
+	printr(os.Stdout, dupe.PrintSelf(groups...))
+	if *f.Rm {
+		s, err := c.DelGroups(groups...)
+		if err != nil {
+			return err
+		}
+		printr(os.Stdout, s)
+		if s := c.DryRunStatus(); s != "" {
+			printl(os.Stdout, s)
+		}
+		if manifest := c.Quarantined(); manifest != "" {
+			printl(os.Stdout, color.Secondary.Sprint("The quarantine manifest is saved to: ")+manifest)
+		}
+	}
+	if !c.Quiet {
+		printl(os.Stdout, c.Status())
+	}
+	return nil
+}
+
 // SetStat sets and stats directories and files to scan, a bucket is the name given to database tables.
 func SetStat(db *bolt.DB, c *dupe.Config, args ...string) error {
 	if db == nil {
@@ -242,6 +505,22 @@ func WalkScan(db *bolt.DB, c *dupe.Config, f *cmd.Flags, args ...string) error {
 	if c == nil {
 		return dupe.ErrNilConfig
 	}
+	format, err := checkFormat(c, f)
+	if err != nil {
+		return err
+	}
+	if err := checkKeep(c); err != nil {
+		return err
+	}
+	if err := c.CheckRemove(); err != nil {
+		return err
+	}
+	if err := checkLink(c, f); err != nil {
+		return err
+	}
+	if err := checkDirs(f); err != nil {
+		return err
+	}
 	// files or directories to compare (these are not saved to database)
 	if err := c.WalkSource(); err != nil {
 		return err
@@ -251,9 +530,15 @@ func WalkScan(db *bolt.DB, c *dupe.Config, f *cmd.Flags, args ...string) error {
 	if err := duplicate.WalkScanSave(db, c, f); err != nil {
 		return err
 	}
+	if format != "" {
+		return writeResults(c, f, format)
+	}
 	if !c.Quiet {
 		printr(os.Stdout, printer.EraseLine())
 	}
+	if dirs(f) {
+		return printDirs(db, c)
+	}
 	// print the found dupes
 	s, err := c.Print()
 	if err != nil {
@@ -271,6 +556,106 @@ func WalkScan(db *bolt.DB, c *dupe.Config, f *cmd.Flags, args ...string) error {
 	return nil
 }
 
+// printDirs prints the identical and subset directories.
+func printDirs(db *bolt.DB, c *dupe.Config) error {
+	groups, subsets, err := c.Dirs(db)
+	if err != nil {
+		return err
+	}
+	printr(os.Stdout, dupe.PrintDirs(groups, subsets))
+	if !c.Quiet {
+		printl(os.Stdout, c.Status())
+	}
+	return nil
+}
+
+// writeResults prints the dupe results in the machine-readable format,
+// after any cleanup commands have run to include the actions taken on the source files.
+func writeResults(c *dupe.Config, f *cmd.Flags, format string) error {
+	results, err := c.Results()
+	if err != nil {
+		return err
+	}
+	if err := duplicate.Cleanup(c, f); err != nil {
+		return err
+	}
+	c.SetActions(results...)
+	return dupe.WriteResults(os.Stdout, format, results...)
+}
+
+// checkFormat returns the output format or an error if it is unknown.
+// A format quiets the config, as the progress, prompts and summaries would corrupt the machine-readable results.
+func checkFormat(c *dupe.Config, f *cmd.Flags) (string, error) {
+	format := format(f)
+	if err := dupe.CheckFormat(format); err != nil {
+		return "", err
+	}
+	if format != "" {
+		c.Quiet = true
+	}
+	return format, nil
+}
+
+// checkKeep returns an error if the keep policy is unknown.
+func checkKeep(c *dupe.Config) error {
+	if c.Keep == "" {
+		return nil
+	}
+	_, err := dupe.ParseKeep(c.Keep)
+	return err
+}
+
+// checkLink returns an error if the link type is unknown,
+// or if the link or reflink options are used together or with any of the delete options.
+func checkLink(c *dupe.Config, f *cmd.Flags) error {
+	kind := link(f)
+	reflink := f != nil && f.Reflink != nil && *f.Reflink
+	if kind == "" && !reflink {
+		return nil
+	}
+	if err := dupe.CheckLink(kind); err != nil {
+		return err
+	}
+	rm := f.Rm != nil && *f.Rm || f.RmPlus != nil && *f.RmPlus || f.Sensen != nil && *f.Sensen
+	if rm || kind != "" && reflink || c.Quarantine != "" || c.Trash {
+		return ErrLinkRM
+	}
+	return nil
+}
+
+// checkDirs returns an error if the dirs option is used with any of the delete, link or format options.
+func checkDirs(f *cmd.Flags) error {
+	if !dirs(f) {
+		return nil
+	}
+	rm := f.Rm != nil && *f.Rm || f.RmPlus != nil && *f.RmPlus || f.Sensen != nil && *f.Sensen
+	if rm || link(f) != "" || f.Reflink != nil && *f.Reflink || format(f) != "" {
+		return ErrDirs
+	}
+	return nil
+}
+
+// dirs returns true when the identical and subset directories are reported instead of files.
+func dirs(f *cmd.Flags) bool {
+	return f != nil && f.Dirs != nil && *f.Dirs
+}
+
+// link returns the link type or an empty string when the duplicates are not linked.
+func link(f *cmd.Flags) string {
+	if f == nil || f.Link == nil {
+		return ""
+	}
+	return *f.Link
+}
+
+// format returns the machine-readable output format or an empty string for the text output.
+func format(f *cmd.Flags) string {
+	if f == nil || f.Format == nil {
+		return ""
+	}
+	return strings.ToLower(*f.Format)
+}
+
 // Help, usage and examples.
 func Help() string {
 	b, w := helper()
@@ -341,21 +726,72 @@ func Search(db *bolt.DB, f *cmd.Flags, test bool, args ...string) error {
 	if len(args) < minArgs {
 		return ErrArgs
 	}
+	format := format(f)
+	if err := dupe.CheckFormat(format); err != nil {
+		return err
+	}
 	term, buckets := args[1], []string{}
 	if count > minArgs {
 		buckets = args[minArgs:]
 	}
-	matches, err := search.Compare(db, f, term, buckets)
+	if f.Fuzzy != nil && *f.Fuzzy {
+		return fuzzy(db, f, format, term, buckets)
+	}
+	pattern, err := search.Pattern(f, term)
 	if err != nil {
 		return err
 	}
-	printr(os.Stdout, dupe.Print(*f.Quiet, *f.Exact, term, matches))
+	matches, err := search.ComparePattern(db, f, pattern, term, buckets)
+	if err != nil {
+		return err
+	}
+	if format != "" {
+		results, err := dupe.SearchResults(db, matches)
+		if err != nil {
+			return err
+		}
+		return dupe.WriteResults(os.Stdout, format, results...)
+	}
+	content := search.Content(f)
+	switch {
+	case pattern != nil:
+		printr(os.Stdout, dupe.PrintPattern(*f.Quiet, pattern, matches))
+	case content:
+		// the checksum of the term is not part of the paths to highlight
+		printr(os.Stdout, dupe.Print(*f.Quiet, *f.Exact, "", matches))
+	default:
+		printr(os.Stdout, dupe.Print(*f.Quiet, *f.Exact, term, matches))
+	}
 	if !*f.Quiet {
 		total := 0
 		if matches != nil {
 			total = len(*matches)
 		}
-		printl(os.Stdout, cmd.SearchSummary(total, term, *f.Exact, *f.Filename))
+		exact, filename := *f.Exact, *f.Filename
+		if content {
+			exact, filename = false, false
+		}
+		printl(os.Stdout, cmd.SearchSummary(total, term, exact, filename))
+	}
+	return nil
+}
+
+// fuzzy prints the stored files that most resemble the search term, from the closest match.
+func fuzzy(db *bolt.DB, f *cmd.Flags, format, term string, buckets []string) error {
+	ranks, err := search.Fuzzy(db, f, term, buckets)
+	if err != nil {
+		return err
+	}
+	if format != "" {
+		results, err := dupe.RankResults(db, ranks)
+		if err != nil {
+			return err
+		}
+		return dupe.WriteResults(os.Stdout, format, results...)
+	}
+	printr(os.Stdout, dupe.PrintRanked(*f.Quiet, *f.Exact, term, ranks))
+	if !*f.Quiet {
+		printl(os.Stdout, cmd.SearchSummary(len(ranks), term, *f.Exact, *f.Filename))
 	}
 	return nil
 }
@@ -382,7 +818,10 @@ func safesize(i int64) uint64 {
 	return uint64(i)
 }
 
-// CleanupDB cleans and compacts the database.
+// CleanupDB cleans the stale items from the buckets and compacts the database.
+// The prune option also cleans the items skipped by the .dupersignore files of the buckets.
+// A dry run only reports the items and does not compact the database.
+// The read-only buckets are not cleaned.
 func CleanupDB(db *bolt.DB, c *dupe.Config) error {
 	if db == nil {
 		return bberr.ErrDatabaseNotOpen
@@ -390,12 +829,22 @@ func CleanupDB(db *bolt.DB, c *dupe.Config) error {
 	if c == nil {
 		return dupe.ErrNilConfig
 	}
-	if err := database.Clean(db, c.Quiet, c.Debug); err != nil {
+	names, err := writable(db, c)
+	if err != nil {
+		return err
+	}
+	if names != nil && len(names) == 0 {
+		c.Debugger("every bucket is read-only, skip the database clean.")
+	} else if err := clean(db, c, names...); err != nil {
 		if b := errors.Is(err, database.ErrNoClean); !b {
 			return err
 		}
 		printer.StderrCR(err)
 	}
+	if c.DryRun {
+		c.Debugger("dry run, skip the database compact.")
+		return nil
+	}
 	if err := database.Compact(db, c.Debug); err != nil {
 		if b := errors.Is(err, database.ErrNoCompact); !b {
 			return err
@@ -404,6 +853,18 @@ func CleanupDB(db *bolt.DB, c *dupe.Config) error {
 	return nil
 }
 
+// clean the stale items from the buckets, and with the prune option, the items skipped by the .dupersignore files.
+// The include and exclude globs only filter a single scan or query, so they never clean the database.
+func clean(db *bolt.DB, c *dupe.Config, buckets ...string) error {
+	if c.Prune {
+		return database.CleanIgnore(db, (&ignore.Filter{}).Skip, c.Quiet, c.Debug, c.DryRun, buckets...)
+	}
+	if c.DryRun {
+		return database.DryClean(db, c.Quiet, c.Debug, buckets...)
+	}
+	return database.Clean(db, c.Quiet, c.Debug, buckets...)
+}
+
 // StatSource checks the path arguments supplied to the dupe command.
 func StatSource(c *dupe.Config) error {
 	if c == nil {
@@ -420,7 +881,11 @@ func StatSource(c *dupe.Config) error {
 		verb = "Bucket"
 	}
 	src := c.GetSource()
-	w := os.Stdout
+	var w io.Writer = os.Stdout
+	if c.Quiet {
+		// keep stdout for the results, such as the machine-readable formats
+		w = os.Stderr
+	}
 	if isDir {
 		printr(w, "Directory to check:")
 	} else {
@@ -467,7 +932,7 @@ func dirPrompt(w io.Writer, c *dupe.Config) error {
 	printf(w, " %s", c.BucketsStr())
 	printl(w)
 	printl(w)
-	if !printer.AskYN("Is this what you want (no will exit)", c.Yes, printer.Yes) {
+	if !printer.AskYNTo(w, "Is this what you want (no will exit)", c.Yes, printer.Yes) {
 		return ErrUserExit
 	}
 	return nil
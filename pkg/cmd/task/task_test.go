@@ -2,14 +2,21 @@
 package task_test
 
 import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/bengarrett/dupers/internal/mock"
 	"github.com/bengarrett/dupers/pkg/cmd"
 	"github.com/bengarrett/dupers/pkg/cmd/task"
+	"github.com/bengarrett/dupers/pkg/database"
 	"github.com/bengarrett/dupers/pkg/dupe"
 	"github.com/nalgeon/be"
+	bolt "go.etcd.io/bbolt"
 )
 
 func TestHelps(t *testing.T) {
@@ -124,6 +131,79 @@ func TestDupe(t *testing.T) {
 	// as they can be done using the Taskfile.yaml
 }
 
+func TestDupe_Format(t *testing.T) {
+	db, path := mock.Database(t)
+	defer db.Close()
+	defer os.Remove(path)
+	bucket1, err := mock.Bucket(t, 1)
+	be.Err(t, err, nil)
+	b, err := os.ReadFile(mock.Item(t, 1))
+	be.Err(t, err, nil)
+	src := t.TempDir()
+	err = os.WriteFile(filepath.Join(src, "copy.txt"), b, mock.PrivateFile)
+	be.Err(t, err, nil)
+	f := cmd.Flags{}
+	f.Define()
+	for _, format := range []string{"json", "csv"} {
+		*f.Format = format
+		c := dupe.Config{Yes: true}
+		// the stats and prompts of a named bucket must not corrupt the results
+		out := stdout(t, func() {
+			err = task.Dupe(db, &c, &f, task.Dupe_, src, bucket1)
+		})
+		be.Err(t, err, nil)
+		switch format {
+		case "json":
+			var results []dupe.Result
+			err = json.Unmarshal([]byte(out), &results)
+			be.Err(t, err, nil)
+			be.Equal(t, len(results), 1)
+			be.Equal(t, results[0].Matches, []string{mock.Item(t, 1)})
+		case "csv":
+			records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+			be.Err(t, err, nil)
+			be.Equal(t, len(records), 2)
+			be.Equal(t, records[1][1], mock.Item(t, 1))
+		}
+	}
+}
+
+// stdout returns everything printed to stdout by the function.
+func stdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	be.Err(t, err, nil)
+	defer r.Close()
+	old := os.Stdout
+	os.Stdout = w
+	defer func() {
+		os.Stdout = old
+	}()
+	fn()
+	_ = w.Close()
+	b, err := io.ReadAll(r)
+	be.Err(t, err, nil)
+	return string(b)
+}
+
+func TestDupeSelf(t *testing.T) {
+	err := task.DupeSelf(nil, nil, "")
+	be.Err(t, err)
+	c := dupe.Config{Test: true, Quiet: true}
+	f := cmd.Flags{}
+	err = task.DupeSelf(&c, &f, "")
+	be.Err(t, err, task.ErrNilFlags)
+	rm, off := true, false
+	f.Rm, f.RmPlus, f.Sensen = &rm, &off, &off
+	err = task.DupeSelf(&c, &f, task.Dupe_, t.TempDir())
+	be.Err(t, err, task.ErrSelfRM)
+	rm = false
+	bucket1, err := mock.Bucket(t, 1)
+	be.Err(t, err, nil)
+	err = task.DupeSelf(&c, &f, task.Dupe_, bucket1)
+	be.Err(t, err, nil)
+}
+
 func TestDatabase(t *testing.T) {
 	err := task.Database(nil, nil, "")
 	be.Err(t, err)
@@ -163,6 +243,30 @@ func TestCleanupDB(t *testing.T) {
 	be.Err(t, err, nil)
 }
 
+func TestCleanupDB_Globs(t *testing.T) {
+	db, path := mock.Database(t)
+	defer func() { _ = db.Close() }()
+	defer os.Remove(path)
+	bucket1, err := mock.Bucket(t, 1)
+	be.Err(t, err, nil)
+	before, err := database.ListRecords(db, bucket1)
+	be.Err(t, err, nil)
+	be.True(t, len(before) > 0)
+	// the include and exclude globs of a single run never clean the stored items
+	c := dupe.Config{Include: []string{"*.jpg"}, Exclude: []string{"*"}}
+	for _, prune := range []bool{false, true} {
+		c.Prune = prune
+		err = task.CleanupDB(db, &c)
+		be.Err(t, err, nil)
+		// the compact closes the database
+		db, err = bolt.Open(path, database.PrivateFile, nil)
+		be.Err(t, err, nil)
+		after, err := database.ListRecords(db, bucket1)
+		be.Err(t, err, nil)
+		be.Equal(t, len(after), len(before))
+	}
+}
+
 func TestStatSource(t *testing.T) {
 	err := task.StatSource(nil)
 	be.Err(t, err)
@@ -13,6 +13,8 @@ import (
 	"strings"
 
 	"github.com/bengarrett/dupers/internal/printer"
+	"github.com/bengarrett/dupers/pkg/database/index"
+	"github.com/bengarrett/dupers/pkg/database/record"
 	"github.com/gookit/color"
 	bolt "go.etcd.io/bbolt"
 	bberr "go.etcd.io/bbolt/errors"
@@ -37,6 +39,9 @@ type Cleaner struct {
 	Items int    // Items is the sum of the bucket items.
 	Finds int    // Finds is the sum of the cleaned items.
 	Errs  int    // Errs is the sum of the items that could not be cleaned.
+
+	DryRun bool                           // DryRun reports the stale items without removing them.
+	Ignore func(bucket, path string) bool // Ignore also cleans the items it returns true for, nil disables it.
 }
 
 func printl(w io.Writer, a ...any) {
@@ -48,6 +53,7 @@ func printf(w io.Writer, format string, a ...any) {
 }
 
 // Clean the stale items from database buckets.
+// Items with values that are not a known record layout are also removed.
 //
 // Returned are the counted items, the finds and the number of errors.
 func (c *Cleaner) Clean(db *bolt.DB) (int, int, int, error) {
@@ -62,6 +68,14 @@ func (c *Cleaner) Clean(db *bolt.DB) (int, int, int, error) {
 		if err := bucket.ForEach(func(k, v []byte) error {
 			c.Items++
 			printStat(c.Debug, c.Quiet, c.Items, c.Total, k)
+			if _, errR := record.Decode(v); errR != nil {
+				printer.Debug(c.Debug, fmt.Sprintf("%s: %s", k, errR))
+				return c.delete(db, k)
+			}
+			if c.Ignore != nil && c.Ignore(c.Name, string(k)) {
+				printer.Debug(c.Debug, fmt.Sprintf("%s: is ignored", k))
+				return c.delete(db, k)
+			}
 			if _, errS := os.Stat(string(k)); errS != nil {
 				f := string(k)
 				if st, err2 := os.Stat(filepath.Dir(f)); err2 == nil {
@@ -70,13 +84,7 @@ func (c *Cleaner) Clean(db *bolt.DB) (int, int, int, error) {
 					}
 				}
 				printer.Debug(c.Debug, fmt.Sprintf("%s: %s", k, errS))
-				if errUp := db.Update(func(tx *bolt.Tx) error {
-					return tx.Bucket([]byte(c.Name)).Delete(k)
-				}); errUp != nil {
-					return errUp
-				}
-				c.Finds++
-				return nil
+				return c.delete(db, k)
 			}
 			return nil
 		}); err != nil {
@@ -93,6 +101,28 @@ func (c *Cleaner) Clean(db *bolt.DB) (int, int, int, error) {
 	return c.Items, c.Finds, c.Errs, nil
 }
 
+// delete the stale item from the bucket.
+// A dry run prints the item instead.
+func (c *Cleaner) delete(db *bolt.DB, k []byte) error {
+	if c.DryRun {
+		if !c.Quiet {
+			printf(os.Stdout, "\r%s: %s\n", color.Secondary.Sprint("would clean"), k)
+		}
+		c.Finds++
+		return nil
+	}
+	if errUp := db.Update(func(tx *bolt.Tx) error {
+		if err := index.Delete(tx, c.Name, k); err != nil {
+			return err
+		}
+		return tx.Bucket([]byte(c.Name)).Delete(k)
+	}); errUp != nil {
+		return errUp
+	}
+	c.Finds++
+	return nil
+}
+
 type Parser struct {
 	Name  string // Name of the bucket to parse.
 	Debug bool   // Debug spams technobabble to stdout.
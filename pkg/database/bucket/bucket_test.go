@@ -11,6 +11,8 @@ import (
 	"github.com/bengarrett/dupers/internal/mock"
 	"github.com/bengarrett/dupers/pkg/database/bucket"
 	"github.com/nalgeon/be"
+	bolt "go.etcd.io/bbolt"
+	bberr "go.etcd.io/bbolt/errors"
 )
 
 func TestParse(t *testing.T) {
@@ -93,6 +95,64 @@ func TestCleaner_Clean(t *testing.T) {
 	be.Equal(t, errs, 0)
 }
 
+func TestCleaner_DryRun(t *testing.T) {
+	db, path := mock.Database(t)
+	defer db.Close()
+	defer os.Remove(path)
+	bucket1, err := mock.Bucket(t, 1)
+	be.Err(t, err, nil)
+	stale := []byte(filepath.Join(bucket1, "stale-item"))
+	err = db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(bucket1)).Put(stale, []byte("stale"))
+	})
+	be.Err(t, err, nil)
+	c := bucket.Cleaner{
+		Name:   bucket1,
+		Quiet:  true,
+		DryRun: true,
+	}
+	items, finds, errs, err := c.Clean(db)
+	be.Err(t, err, nil)
+	be.Equal(t, items, 4)
+	be.Equal(t, finds, 1)
+	be.Equal(t, errs, 0)
+	err = db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(bucket1)).Get(stale) == nil {
+			return bberr.ErrBucketNotFound
+		}
+		return nil
+	})
+	be.Err(t, err, nil)
+}
+
+func TestCleaner_Ignore(t *testing.T) {
+	db, path := mock.Database(t)
+	defer db.Close()
+	defer os.Remove(path)
+	bucket1, err := mock.Bucket(t, 1)
+	be.Err(t, err, nil)
+	c := bucket.Cleaner{
+		Name:   bucket1,
+		Quiet:  true,
+		DryRun: true,
+		Ignore: func(string, string) bool { return true },
+	}
+	items, finds, errs, err := c.Clean(db)
+	be.Err(t, err, nil)
+	be.True(t, items > 0)
+	be.Equal(t, finds, items)
+	be.Equal(t, errs, 0)
+	c = bucket.Cleaner{
+		Name:   bucket1,
+		Quiet:  true,
+		DryRun: true,
+		Ignore: func(_, path string) bool { return strings.HasSuffix(path, ".txt") },
+	}
+	_, finds, _, err = c.Clean(db)
+	be.Err(t, err, nil)
+	be.Equal(t, finds, 0)
+}
+
 func TestAbs(t *testing.T) {
 	s, err := bucket.Abs("")
 	be.Err(t, err)
@@ -15,6 +15,8 @@ import (
 
 	"github.com/bengarrett/dupers/internal/printer"
 	"github.com/bengarrett/dupers/pkg/database/bucket"
+	"github.com/bengarrett/dupers/pkg/database/index"
+	"github.com/bengarrett/dupers/pkg/database/record"
 	"github.com/dustin/go-humanize"
 	"github.com/gookit/color"
 	bolt "go.etcd.io/bbolt"
@@ -33,6 +35,8 @@ type (
 	Lists map[Filepath][32]byte
 	// Matches are a collection of fetched filepaths and the bucket they were sourced from.
 	Matches map[Filepath]Bucket
+	// Records are a collection of fetched filepaths and their checksum and file stat data.
+	Records map[Filepath]record.Record
 )
 
 const (
@@ -41,6 +45,8 @@ const (
 
 	NotFound = "This is okay as one will be created when using the dupe or search commands."
 
+	Env = "DUPERS_DB" // Env is the environment variable for the path of the database file.
+
 	backupTime = "20060102-150405"
 	boltName   = "dupers.db"
 	csvName    = "dupers-export.csv"
@@ -51,6 +57,7 @@ const (
 )
 
 var (
+	ErrCatalog   = errors.New("catalog name is invalid, use letters, numbers, dashes, underscores or dots")
 	ErrEmpty     = errors.New("database is empty and contains no items")
 	ErrNoCompact = errors.New("compression has not reduced the database size")
 	ErrNoClean   = errors.New("database has nothing to clean")
@@ -80,6 +87,7 @@ func AbsB(name string) ([]byte, error) {
 }
 
 // All returns every stored bucket within the database.
+// The search index is not a stored bucket.
 func All(db *bolt.DB) ([]string, error) {
 	if db == nil {
 		return nil, bberr.ErrDatabaseNotOpen
@@ -87,6 +95,9 @@ func All(db *bolt.DB) ([]string, error) {
 	var names []string
 	if err := db.View(func(tx *bolt.Tx) error {
 		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			if string(name) == index.Name {
+				return nil
+			}
 			if v := tx.Bucket(name); v == nil {
 				return fmt.Errorf("%w: %s", bberr.ErrBucketNotFound, string(name))
 			}
@@ -124,6 +135,18 @@ func Check() (int64, error) {
 	return i.Size(), nil
 }
 
+// Ignore removes the matches that the skip function returns true for, using their bucket and path.
+func (m Matches) Ignore(skip func(bucket, path string) bool) {
+	if skip == nil {
+		return
+	}
+	for path, bucket := range m {
+		if skip(string(bucket), string(path)) {
+			delete(m, path)
+		}
+	}
+}
+
 // Exist returns an error if the bucket does not exists in the database.
 func Exist(db *bolt.DB, bucket string) error {
 	if db == nil {
@@ -141,6 +164,23 @@ func Exist(db *bolt.DB, bucket string) error {
 // Clean the stale items from database buckets.
 // Stale items are file pointers that no longer exist on the host file system.
 func Clean(db *bolt.DB, quiet, debug bool, buckets ...string) error {
+	return clean(db, nil, quiet, debug, false, buckets...)
+}
+
+// DryClean reports the stale items that Clean would remove from the database buckets,
+// without making any changes to the database.
+func DryClean(db *bolt.DB, quiet, debug bool, buckets ...string) error {
+	return clean(db, nil, quiet, debug, true, buckets...)
+}
+
+// CleanIgnore cleans the stale items and the items that the skip function returns true for
+// from the database buckets, the function is given the bucket and the path of each item.
+// When dryRun is true, the items are reported without making any changes to the database.
+func CleanIgnore(db *bolt.DB, skip func(bucket, path string) bool, quiet, debug, dryRun bool, buckets ...string) error {
+	return clean(db, skip, quiet, debug, dryRun, buckets...)
+}
+
+func clean(db *bolt.DB, skip func(bucket, path string) bool, quiet, debug, dryRun bool, buckets ...string) error {
 	if db == nil {
 		return bberr.ErrDatabaseNotOpen
 	}
@@ -159,17 +199,19 @@ func Clean(db *bolt.DB, quiet, debug bool, buckets ...string) error {
 	}
 
 	for _, name := range cleaned {
-		cnt, errs, finds, err = cleanBucket(db, name, cnt, errs, finds, total, quiet, debug)
+		cnt, errs, finds, err = cleanBucket(db, skip, name, cnt, errs, finds, total, quiet, debug, dryRun)
 		if err != nil {
 			return err
 		}
 	}
 
-	return handleCleanResult(quiet, debug, len(cleaned), errs, finds)
+	return handleCleanResult(quiet, debug, dryRun, len(cleaned), errs, finds)
 }
 
 // cleanBucket handles the cleaning process for a single bucket.
-func cleanBucket(db *bolt.DB, name string, cnt, errs, finds, total int, quiet, debug bool) (int, int, int, error) {
+func cleanBucket(db *bolt.DB, skip func(bucket, path string) bool, name string, cnt, errs, finds, total int,
+	quiet, debug, dryRun bool,
+) (int, int, int, error) {
 	var abs string
 	var cont bool
 
@@ -184,31 +226,46 @@ func cleanBucket(db *bolt.DB, name string, cnt, errs, finds, total int, quiet, d
 
 	if cont {
 		// Bucket directory doesn't exist, remove the entire bucket
-		return removeEmptyBucket(db, name, cnt, errs, finds, debug)
+		return removeEmptyBucket(db, name, cnt, errs, finds, debug, dryRun)
 	}
 
 	// Clean the bucket
 	cleaner := bucket.Cleaner{
-		Name:  abs,
-		Debug: debug,
-		Quiet: quiet,
-		Items: cnt,
-		Total: total,
-		Finds: finds,
-		Errs:  errs,
-	}
+		Name:   abs,
+		Debug:  debug,
+		Quiet:  quiet,
+		Items:  cnt,
+		Total:  total,
+		Finds:  finds,
+		Errs:   errs,
+		DryRun: dryRun,
+		Ignore: skip,
+	}
+	before := finds
 	cnt, finds, errs, err := cleaner.Clean(db)
 	if err != nil {
 		return cnt, errs, finds, err
 	}
 
 	// Check if bucket is empty after cleaning and remove it
-	return checkAndRemoveEmptyBucket(db, abs, cnt, errs, finds, debug)
+	stale := 0
+	if dryRun {
+		// the stale items are still in the bucket
+		stale = finds - before
+	}
+	return checkAndRemoveEmptyBucket(db, abs, cnt, errs, finds, stale, debug, dryRun)
 }
 
 // removeEmptyBucket removes a bucket that doesn't exist on the filesystem.
-func removeEmptyBucket(db *bolt.DB, name string, cnt, errs, finds int, debug bool) (int, int, int, error) {
+func removeEmptyBucket(db *bolt.DB, name string, cnt, errs, finds int, debug, dryRun bool) (int, int, int, error) {
+	if dryRun {
+		printer.Debug(debug, "would remove empty bucket: "+name)
+		return cnt, errs, finds + 1, nil
+	}
 	err := db.Update(func(tx *bolt.Tx) error {
+		if err := index.Drop(tx, name); err != nil {
+			return err
+		}
 		return tx.DeleteBucket([]byte(name))
 	})
 	if err != nil {
@@ -220,7 +277,10 @@ func removeEmptyBucket(db *bolt.DB, name string, cnt, errs, finds int, debug boo
 }
 
 // checkAndRemoveEmptyBucket checks if a bucket is empty and removes it if so.
-func checkAndRemoveEmptyBucket(db *bolt.DB, abs string, cnt, errs, finds int, debug bool) (int, int, int, error) {
+// The stale items are the items that a dry run would have removed from the bucket.
+func checkAndRemoveEmptyBucket(db *bolt.DB, abs string, cnt, errs, finds, stale int,
+	debug, dryRun bool,
+) (int, int, int, error) {
 	var itemCount int
 	err := db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(abs))
@@ -237,8 +297,8 @@ func checkAndRemoveEmptyBucket(db *bolt.DB, abs string, cnt, errs, finds int, de
 		return cnt, errs + 1, finds, nil //nolint:nilerr
 	}
 
-	if itemCount == 0 {
-		return removeEmptyBucket(db, abs, cnt, errs, finds, debug)
+	if itemCount-stale == 0 {
+		return removeEmptyBucket(db, abs, cnt, errs, finds, debug, dryRun)
 	}
 
 	return cnt, errs, finds, nil
@@ -255,7 +315,7 @@ func countBucketItems(b *bolt.Bucket) (int, error) {
 }
 
 // handleCleanResult handles the final result of the cleaning process.
-func handleCleanResult(quiet, debug bool, totalBuckets, errs, finds int) error {
+func handleCleanResult(quiet, debug, dryRun bool, totalBuckets, errs, finds int) error {
 	if quiet {
 		return nil
 	}
@@ -268,6 +328,10 @@ func handleCleanResult(quiet, debug bool, totalBuckets, errs, finds int) error {
 		printl(w, "")
 		return ErrNoClean
 	}
+	if finds > 0 && dryRun {
+		printf(w, "\rThe database would remove %d stale items, nothing was changed\n", finds)
+		return nil
+	}
 	if finds > 0 {
 		printf(w, "\rThe database removed %d stale items\n", finds)
 		return nil
@@ -311,8 +375,8 @@ func Compact(db *bolt.DB, debug bool) error {
 	}
 	printer.Debug(debug, "running database compact")
 
-	// make a temporary database
-	f, err := os.CreateTemp(os.TempDir(), "dupers-*.db")
+	// make a temporary database next to the original
+	f, err := os.CreateTemp(filepath.Dir(db.Path()), "dupers-*.db")
 	if err != nil {
 		return err
 	}
@@ -388,6 +452,15 @@ func CompareBaseNoCase(db *bolt.DB, s string, buckets ...string) (*Matches, erro
 	return compare(db, ignoreCase, pathBase, []byte(s), buckets...)
 }
 
+// CompareSum finds the stored filenames and paths with a SHA256 checksum that matches the sum.
+// Items with values that are not a known record layout are skipped.
+func CompareSum(db *bolt.DB, sum [32]byte, buckets ...string) (*Matches, error) {
+	return search(db, nil, func(_, value []byte) bool {
+		r, err := record.Decode(value)
+		return err == nil && r.Sum == sum
+	}, buckets...)
+}
+
 // CompareNoCase finds case insensitive matches of the string contained within the stored filenames and paths.
 func CompareNoCase(db *bolt.DB, s string, buckets ...string) (*Matches, error) {
 	const ignoreCase, pathBase = true, false
@@ -401,6 +474,26 @@ func compare(db *bolt.DB, ignoreCase, pathBase bool, term []byte, buckets ...str
 	if len(term) == 0 {
 		return nil, ErrNoTerm
 	}
+	s := term
+	if ignoreCase {
+		s = bytes.ToLower(term)
+	}
+	return search(db, term, func(key, _ []byte) bool {
+		k := compareKey(key, ignoreCase)
+		if pathBase {
+			k = []byte(filepath.Base(string(k)))
+		}
+		return bytes.Contains(k, s)
+	}, buckets...)
+}
+
+// search returns the stored filenames and paths of the buckets that are matched by the func,
+// which is given the key and the value of each item.
+// When the term is not nil, the search index of the buckets narrows the items to match.
+func search(db *bolt.DB, term []byte, match func(key, value []byte) bool, buckets ...string) (*Matches, error) {
+	if db == nil {
+		return nil, bberr.ErrDatabaseNotOpen
+	}
 	checked, err := checker(db, buckets)
 	if err != nil {
 		return nil, err
@@ -416,25 +509,20 @@ func compare(db *bolt.DB, ignoreCase, pathBase bool, term []byte, buckets ...str
 			if b == nil {
 				return bberr.ErrBucketNotFound
 			}
-			s := term
-			if ignoreCase {
-				s = bytes.ToLower(term)
-			}
-			err = b.ForEach(func(key, _ []byte) error {
-				k := compareKey(key, ignoreCase)
-				if pathBase {
-					k = []byte(filepath.Base(string(k)))
-					if bytes.Contains(k, s) {
+			if keys, ok := index.Candidates(tx, string(abs), term); ok {
+				for _, key := range keys {
+					if value := b.Get(key); value != nil && match(key, value) {
 						finds[Filepath(key)] = Bucket(bucket)
 					}
-					return nil
 				}
-				if bytes.Contains(k, s) {
+				return nil
+			}
+			return b.ForEach(func(key, value []byte) error {
+				if match(key, value) {
 					finds[Filepath(key)] = Bucket(bucket)
 				}
 				return nil
 			})
-			return err
 		})
 		if err != nil {
 			if errors.Is(err, bberr.ErrBucketNotFound) {
@@ -471,6 +559,36 @@ func compareKey(key []byte, ignoreCase bool) []byte {
 	return k
 }
 
+// Rebuild the search index of the named buckets or every bucket, and return the number of indexed items.
+// The index narrows the stored filenames and paths compared by the search terms.
+func Rebuild(db *bolt.DB, buckets ...string) (int, error) {
+	if db == nil {
+		return 0, bberr.ErrDatabaseNotOpen
+	}
+	checked, err := checker(db, buckets)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, name := range checked {
+		abs, err := Abs(name)
+		if err != nil {
+			return total, err
+		}
+		if err := db.Update(func(tx *bolt.Tx) error {
+			cnt, err := index.Build(tx, abs)
+			total += cnt
+			return err
+		}); err != nil {
+			if errors.Is(err, bberr.ErrBucketNotFound) {
+				return total, fmt.Errorf("%w: '%s'", err, abs)
+			}
+			return total, err
+		}
+	}
+	return total, nil
+}
+
 // Count the number of records in the named bucket.
 func Count(db *bolt.DB, name string) (int, error) {
 	if db == nil {
@@ -479,8 +597,58 @@ func Count(db *bolt.DB, name string) (int, error) {
 	return bucket.Count(db, name)
 }
 
-// DB returns the absolute path of the database.
-func DB() (string, error) {
+// location is the path of the database file set by Use, when empty DB uses the user config directory.
+var location string
+
+// Use the named file as the database, an empty name restores the default location.
+func Use(name string) error {
+	if name == "" {
+		location = ""
+		return nil
+	}
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return err
+	}
+	location = abs
+	return nil
+}
+
+// Catalog uses the named catalog, which is a database file of that name in the dupers directory.
+// The catalogs keep independent buckets, so that different projects can use their own databases.
+func Catalog(name string) error {
+	invalid := func(r rune) bool {
+		return (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '-' && r != '_' && r != '.'
+	}
+	if strings.Trim(name, ".") == "" || strings.ContainsFunc(name, invalid) {
+		return fmt.Errorf("%w: %q", ErrCatalog, name)
+	}
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+	return Use(filepath.Join(dir, name+filepath.Ext(boltName)))
+}
+
+// Dir returns the absolute path of the dupers directory within the user config directory.
+// The directory is created if it doesn't exist.
+func Dir() (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	// create database directory if it doesn't exist
+	if _, err = os.Stat(dir); os.IsNotExist(err) {
+		if errMk := os.MkdirAll(dir, PrivateDir); errMk != nil {
+			return "", fmt.Errorf("cannot create database directory: %w: %s", errMk, dir)
+		}
+	}
+	return dir, nil
+}
+
+// ConfigDir returns the absolute path of the dupers directory within the user config directory,
+// the same as Dir, but without creating the directory when it does not exist.
+func ConfigDir() (string, error) {
 	dir, err := os.UserConfigDir()
 	if err != nil {
 		dir, err = os.UserHomeDir()
@@ -488,16 +656,48 @@ func DB() (string, error) {
 			return "", err
 		}
 	}
-	dir = filepath.Join(dir, subdir)
-	// create database directory if it doesn't exist
-	if _, err = os.Stat(dir); os.IsNotExist(err) {
-		if errMk := os.MkdirAll(dir, PrivateDir); errMk != nil {
-			return "", fmt.Errorf("cannot create database directory: %w: %s", errMk, dir)
+	return filepath.Join(dir, subdir), nil
+}
+
+// Location returns the absolute path of the database, the same as DB,
+// but without creating the database or its directory when they do not exist.
+func Location() (string, error) {
+	if location != "" {
+		return location, nil
+	}
+	if env := os.Getenv(Env); env != "" {
+		return filepath.Abs(env)
+	}
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, boltName), nil
+}
+
+// DB returns the absolute path of the database.
+// The location set by Use or Catalog takes priority over the DUPERS_DB environment variable,
+// otherwise the database is kept in the user config directory.
+func DB() (string, error) {
+	path := location
+	if env := os.Getenv(Env); path == "" && env != "" {
+		abs, err := filepath.Abs(env)
+		if err != nil {
+			return "", err
+		}
+		path = abs
+	}
+	if path == "" {
+		dir, err := Dir()
+		if err != nil {
+			return "", err
 		}
+		path = filepath.Join(dir, boltName)
+	} else if err := os.MkdirAll(filepath.Dir(path), PrivateDir); err != nil {
+		return "", fmt.Errorf("cannot create database directory: %w: %s", err, filepath.Dir(path))
 	}
 	// create a new database if it doesn't exist, this prevents
 	// posix system returning the error a "bad file descriptor" when reading
-	path := filepath.Join(dir, boltName)
 	stat, errp := os.Stat(path)
 	if os.IsNotExist(errp) {
 		if err := Create(path); err != nil {
@@ -618,6 +818,9 @@ func info(db *bolt.DB, w *tabwriter.Writer) (*tabwriter.Writer, uint64, error) {
 	items, cnt, sizes := make(item), 0, uint64(0)
 	if err := db.View(func(tx *bolt.Tx) error {
 		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			if string(name) == index.Name {
+				return nil
+			}
 			v := tx.Bucket(name)
 			if v == nil {
 				return fmt.Errorf("%w: %s", bberr.ErrBucketNotFound, string(name))
@@ -666,6 +869,7 @@ func info(db *bolt.DB, w *tabwriter.Writer) (*tabwriter.Writer, uint64, error) {
 }
 
 // List returns the filepaths and SHA256 checksums stored in the bucket.
+// Items with values that are not a known record layout are skipped.
 func List(db *bolt.DB, bucket string) (Lists, error) {
 	if db == nil {
 		return nil, bberr.ErrDatabaseNotOpen
@@ -676,10 +880,12 @@ func List(db *bolt.DB, bucket string) (Lists, error) {
 		if b == nil {
 			return bberr.ErrBucketNotFound
 		}
-		h := [32]byte{}
 		err := b.ForEach(func(k, v []byte) error {
-			copy(h[:], v)
-			lists[Filepath(k)] = h
+			r, err := record.Decode(v)
+			if err != nil {
+				return nil //nolint:nilerr
+			}
+			lists[Filepath(k)] = r.Sum
 			return nil
 		})
 		return err
@@ -689,6 +895,33 @@ func List(db *bolt.DB, bucket string) (Lists, error) {
 	return lists, nil
 }
 
+// ListRecords returns the filepaths, SHA256 checksums and any file stat data stored in the bucket.
+// Items with values that are not a known record layout are skipped.
+func ListRecords(db *bolt.DB, bucket string) (Records, error) {
+	if db == nil {
+		return nil, bberr.ErrDatabaseNotOpen
+	}
+	records := make(Records)
+	if err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return bberr.ErrBucketNotFound
+		}
+		err := b.ForEach(func(k, v []byte) error {
+			r, err := record.Decode(v)
+			if err != nil {
+				return nil //nolint:nilerr
+			}
+			records[Filepath(k)] = r
+			return nil
+		})
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
 // Rename the named bucket in the database to use a new, target directory path.
 func Rename(db *bolt.DB, name, target string) error {
 	if db == nil {
@@ -711,6 +944,9 @@ func Rename(db *bolt.DB, name, target string) error {
 		}); errPut != nil {
 			return errPut
 		}
+		if errIdx := index.Rename(tx, name, target); errIdx != nil {
+			return errIdx
+		}
 		return tx.DeleteBucket([]byte(name))
 	})
 }
@@ -724,6 +960,9 @@ func Remove(db *bolt.DB, name string) error {
 		if b := tx.Bucket([]byte(name)); b == nil {
 			return bberr.ErrBucketNotFound
 		}
+		if err := index.Drop(tx, name); err != nil {
+			return err
+		}
 		return tx.DeleteBucket([]byte(name))
 	})
 }
@@ -1,8 +1,10 @@
 package database_test
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/bengarrett/dupers/pkg/database"
@@ -284,3 +286,108 @@ func TestDupeMultipleBuckets(t *testing.T) {
 		t.Fatal("Files should have identical content for duplicate testing")
 	}
 }
+
+// TestUse tests the database location can be changed and restored.
+func TestUse(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "sub", "test.db")
+	if err := database.Use(name); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = database.Use("")
+	}()
+	path, err := database.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != name {
+		t.Errorf("DB() = %q, want %q", path, name)
+	}
+	if _, err := os.Stat(name); err != nil {
+		t.Errorf("DB() should create the database: %v", err)
+	}
+	if err := database.Use(""); err != nil {
+		t.Fatal(err)
+	}
+	if path, _ = database.DB(); path == name {
+		t.Errorf("Use(\"\") should restore the default location")
+	}
+}
+
+// TestLocation tests the database location is returned without creating the database.
+func TestLocation(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("AppData", dir)
+	t.Setenv(database.Env, "")
+	path, err := database.Location()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(path) != "dupers.db" || !strings.HasPrefix(path, dir) {
+		t.Errorf("Location() = %q, want dupers.db in %q", path, dir)
+	}
+	if _, err := os.Stat(filepath.Dir(path)); !os.IsNotExist(err) {
+		t.Errorf("Location() should not create the database directory: %v", err)
+	}
+	env := filepath.Join(dir, "env.db")
+	t.Setenv(database.Env, env)
+	if path, _ = database.Location(); path != env {
+		t.Errorf("Location() = %q, want %q", path, env)
+	}
+	if _, err := os.Stat(env); !os.IsNotExist(err) {
+		t.Errorf("Location() should not create the database: %v", err)
+	}
+	name := filepath.Join(dir, "use.db")
+	if err := database.Use(name); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = database.Use("")
+	}()
+	if path, _ = database.Location(); path != name {
+		t.Errorf("Location() = %q, want %q", path, name)
+	}
+}
+
+// TestCatalog tests the named catalogs and the environment variable location.
+func TestCatalog(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("AppData", dir)
+	defer func() {
+		_ = database.Use("")
+	}()
+	for _, name := range []string{"", "..", "a/b", "photos?"} {
+		if err := database.Catalog(name); !errors.Is(err, database.ErrCatalog) {
+			t.Errorf("Catalog(%q) error = %v, want %v", name, err, database.ErrCatalog)
+		}
+	}
+	if err := database.Catalog("photos"); err != nil {
+		t.Fatal(err)
+	}
+	path, err := database.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(path) != "photos.db" || !strings.HasPrefix(path, dir) {
+		t.Errorf("DB() = %q, want photos.db in %q", path, dir)
+	}
+	// the environment variable is used without a catalog or a named file
+	env := filepath.Join(dir, "env.db")
+	t.Setenv(database.Env, env)
+	if err := database.Use(""); err != nil {
+		t.Fatal(err)
+	}
+	if path, _ = database.DB(); path != env {
+		t.Errorf("DB() = %q, want %q", path, env)
+	}
+	if err := database.Catalog("photos"); err != nil {
+		t.Fatal(err)
+	}
+	if path, _ = database.DB(); path == env {
+		t.Errorf("DB() = %q, the catalog should override the environment variable", path)
+	}
+}
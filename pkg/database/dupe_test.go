@@ -3,16 +3,23 @@ package database_test
 
 import (
 	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/bengarrett/dupers/internal/mock"
 	"github.com/bengarrett/dupers/pkg/database"
 	"github.com/bengarrett/dupers/pkg/dupe"
+	"github.com/bengarrett/dupers/pkg/dupe/ignore"
+	"github.com/bengarrett/dupers/pkg/dupe/journal"
 	"github.com/bengarrett/dupers/pkg/dupe/parse"
 	"github.com/gookit/color"
 	"github.com/nalgeon/be"
@@ -33,6 +40,43 @@ func TestConfig_Print(t *testing.T) {
 	be.True(t, ok)
 }
 
+func TestConfig_PrintPrune(t *testing.T) {
+	color.Enable = false
+	tmp := t.TempDir()
+	large := func(name string, last byte) string {
+		t.Helper()
+		b := make([]byte, parse.PartSize*3)
+		b[len(b)-1] = last
+		path := filepath.Join(tmp, name)
+		err := os.WriteFile(path, b, mock.PrivateFile)
+		be.Err(t, err, nil)
+		return path
+	}
+	item1 := mock.Item(t, 1)
+	stored := large("stored", 'a')
+	c := dupe.Config{Test: true}
+	for _, name := range []string{item1, stored} {
+		sum, err := parse.Read(name)
+		be.Err(t, err, nil)
+		info, err := os.Stat(name)
+		be.Err(t, err, nil)
+		c.Add(sum, name)
+		c.SetSize(name, info.Size())
+	}
+	// a partial checksum collision is fully hashed and matched
+	c.Sources = []string{item1, mock.Item(t, 2), large("copy", 'a'), large("differs", 'b')}
+	s, err := c.Print()
+	be.Err(t, err, nil)
+	be.Equal(t, strings.Count(s, "Match"), 1)
+	be.True(t, strings.Contains(s, filepath.Join(tmp, "copy")))
+	be.True(t, !strings.Contains(s, filepath.Join(tmp, "differs")))
+	// unknown sizes skip pruning
+	c.Sizes = nil
+	s, err = c.Print()
+	be.Err(t, err, nil)
+	be.Equal(t, strings.Count(s, "Match"), 1)
+}
+
 func copyfile(t *testing.T, i int) string {
 	t.Helper()
 	item := mock.Item(t, i)
@@ -62,11 +106,378 @@ func TestConfig_Remove(t *testing.T) {
 	be.Err(t, err, nil)
 	c.Compare = make(parse.Checksums)
 	c.Compare[sum] = dest
+	// a source that only matches itself is the last copy and is kept
+	s, err = c.DelDupeFiles()
+	be.Err(t, err, nil)
+	ok = strings.Contains(s, "removed:")
+	be.True(t, !ok)
+	_, err = os.Stat(dest)
+	be.Err(t, err, nil)
 	// test remove
+	data, err := os.ReadFile(dest)
+	be.Err(t, err, nil)
+	other := filepath.Join(t.TempDir(), filepath.Base(dest))
+	err = os.WriteFile(other, data, mock.PrivateFile)
+	be.Err(t, err, nil)
+	c.Add(sum, other)
 	s, err = c.DelDupeFiles()
 	be.Err(t, err, nil)
 	ok = strings.Contains(s, "removed:")
 	be.True(t, ok)
+	_, err = os.Stat(other)
+	be.Err(t, err, nil)
+}
+
+func TestParseKeep(t *testing.T) {
+	for _, name := range []string{"oldest", "NEWEST", " shortest ", "longest", "glob:*.txt"} {
+		_, err := dupe.ParseKeep(name)
+		be.Err(t, err, nil)
+	}
+	for _, name := range []string{"", "first", "bucket:", "glob:", "glob:[", "oldest:x"} {
+		_, err := dupe.ParseKeep(name)
+		be.Err(t, err, dupe.ErrKeep)
+	}
+	k, err := dupe.ParseKeep("bucket:" + t.TempDir())
+	be.Err(t, err, nil)
+	be.Equal(t, k.Policy, dupe.KeepBucket)
+}
+
+func TestKeep_Choose(t *testing.T) {
+	tmp := t.TempDir()
+	old := filepath.Join(tmp, "archive", "file.txt")
+	nw := filepath.Join(tmp, "new.txt")
+	long := filepath.Join(tmp, "archive", "nested", "file.txt")
+	for i, name := range []string{old, nw, long} {
+		err := os.MkdirAll(filepath.Dir(name), mock.PrivateDir)
+		be.Err(t, err, nil)
+		err = os.WriteFile(name, []byte("keep"), mock.PrivateFile)
+		be.Err(t, err, nil)
+		mod := time.Date(2020, 1, 1+i, 0, 0, 0, 0, time.UTC)
+		err = os.Chtimes(name, mod, mod)
+		be.Err(t, err, nil)
+	}
+	tests := []struct {
+		policy string
+		want   string
+	}{
+		{"oldest", old},
+		{"newest", long},
+		{"shortest", nw},
+		{"longest", long},
+		{"bucket:" + filepath.Join(tmp, "archive"), old},
+		{"glob:new.*", nw},
+		{"glob:" + filepath.Join(tmp, "archive", "*", "*"), long},
+	}
+	for _, tt := range tests {
+		k, err := dupe.ParseKeep(tt.policy)
+		be.Err(t, err, nil)
+		got, err := k.Choose(long, nw, old)
+		be.Err(t, err, nil)
+		be.Equal(t, got, tt.want)
+	}
+	k, err := dupe.ParseKeep("glob:*.jpg")
+	be.Err(t, err, nil)
+	_, err = k.Choose(old, nw)
+	be.Err(t, err, dupe.ErrNoKeep)
+}
+
+func TestConfig_RemoveKeep(t *testing.T) {
+	color.Enable = false
+	src, bucket := t.TempDir(), t.TempDir()
+	source := filepath.Join(src, "file.txt")
+	oldest := filepath.Join(bucket, "file.txt")
+	other := filepath.Join(bucket, "copy.txt")
+	stale := filepath.Join(bucket, "stale.txt")
+	for i, name := range []string{oldest, other, source, stale} {
+		err := os.WriteFile(name, []byte("remove keep"), mock.PrivateFile)
+		be.Err(t, err, nil)
+		mod := time.Date(2020, 1, 1+i, 0, 0, 0, 0, time.UTC)
+		err = os.Chtimes(name, mod, mod)
+		be.Err(t, err, nil)
+	}
+	sum, err := parse.Read(source)
+	be.Err(t, err, nil)
+	c := dupe.Config{Test: true, Keep: "oldest"}
+	c.Sources = append(c.Sources, source)
+	c.Add(sum, oldest)
+	c.Add(sum, other)
+	c.Add(sum, stale)
+	// the stale item no longer matches and must not be removed
+	err = os.WriteFile(stale, []byte("changed"), mock.PrivateFile)
+	be.Err(t, err, nil)
+	s, err := c.DelDupeFiles()
+	be.Err(t, err, nil)
+	be.Equal(t, strings.Count(s, "removed:"), 2)
+	for name, exist := range map[string]bool{oldest: true, stale: true, source: false, other: false} {
+		_, err := os.Stat(name)
+		be.Equal(t, err == nil, exist)
+	}
+	be.Equal(t, c.Action(source), dupe.ActRemoved)
+	c.Keep = "first"
+	_, err = c.DelDupeFiles()
+	be.Err(t, err, dupe.ErrKeep)
+}
+
+func TestConfig_RemoveKeepReadOnly(t *testing.T) {
+	color.Enable = false
+	src, bucket := t.TempDir(), t.TempDir()
+	source := filepath.Join(src, "file.txt")
+	copied := filepath.Join(bucket, "file.txt")
+	for i, name := range []string{source, copied} {
+		err := os.WriteFile(name, []byte("read-only keep"), mock.PrivateFile)
+		be.Err(t, err, nil)
+		mod := time.Date(2020, 1, 1+i, 0, 0, 0, 0, time.UTC)
+		err = os.Chtimes(name, mod, mod)
+		be.Err(t, err, nil)
+	}
+	sum, err := parse.Read(source)
+	be.Err(t, err, nil)
+	// the oldest file is the source, so the policy would remove the copy in the bucket
+	c := dupe.Config{Test: true, Keep: "oldest"}
+	err = c.SetBuckets(bucket)
+	be.Err(t, err, nil)
+	c.Options = map[string]dupe.Options{bucket: {ReadOnly: true}}
+	c.Sources = append(c.Sources, source)
+	c.Add(sum, copied)
+	s, err := c.DelDupeFiles()
+	be.Err(t, err, nil)
+	be.Equal(t, strings.Count(s, "removed:"), 0)
+	for _, name := range []string{source, copied} {
+		_, err := os.Stat(name)
+		be.Err(t, err, nil)
+	}
+}
+
+func TestConfig_DelGroups(t *testing.T) {
+	color.Enable = false
+	tmp := t.TempDir()
+	short := filepath.Join(tmp, "a.txt")
+	long := filepath.Join(tmp, "sub", "a.txt")
+	err := os.MkdirAll(filepath.Dir(long), mock.PrivateDir)
+	be.Err(t, err, nil)
+	for _, name := range []string{short, long} {
+		err := os.WriteFile(name, []byte("delete groups"), mock.PrivateFile)
+		be.Err(t, err, nil)
+	}
+	c := dupe.Config{Test: true, Keep: "longest"}
+	err = c.SetSource(tmp)
+	be.Err(t, err, nil)
+	err = c.WalkSource()
+	be.Err(t, err, nil)
+	groups, err := c.Self()
+	be.Err(t, err, nil)
+	be.Equal(t, len(groups), 1)
+	s, err := c.DelGroups(groups...)
+	be.Err(t, err, nil)
+	be.Equal(t, strings.Count(s, "removed:"), 1)
+	_, err = os.Stat(short)
+	be.Err(t, err, fs.ErrNotExist)
+	_, err = os.Stat(long)
+	be.Err(t, err, nil)
+}
+
+func TestConfig_RemoveQuarantine(t *testing.T) {
+	color.Enable = false
+	dest := copyfile(t, 1)
+	defer os.Remove(dest)
+	dir := t.TempDir()
+	c := dupe.Config{Test: true, Quarantine: dir}
+	c.Sources = append(c.Sources, dest)
+	sum, err := parse.Read(dest)
+	be.Err(t, err, nil)
+	// the original mock item is the surviving copy
+	c.Add(sum, mock.Item(t, 1))
+	be.Equal(t, c.Quarantined(), "")
+	s, err := c.DelDupeFiles()
+	be.Err(t, err, nil)
+	be.True(t, strings.Contains(s, "quarantined:"))
+	be.Equal(t, c.Action(dest), dupe.ActQuarantined)
+	_, err = os.Stat(dest)
+	be.Err(t, err, fs.ErrNotExist)
+	manifest := c.Quarantined()
+	be.True(t, strings.HasPrefix(manifest, dir))
+	_, err = os.Stat(manifest)
+	be.Err(t, err, nil)
+	// the quarantine directory cannot be within the source
+	err = c.SetSource(filepath.Dir(dir))
+	be.Err(t, err, nil)
+	err = c.CheckRemove()
+	be.Err(t, err, dupe.ErrQuarantine)
+	c.Trash = true
+	err = c.CheckRemove()
+	be.Err(t, err, dupe.ErrTrash)
+}
+
+func TestConfig_RemoveTrash(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the trash is only tested on linux")
+	}
+	color.Enable = false
+	data := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", data)
+	dest := copyfile(t, 1)
+	c := dupe.Config{Test: true, Trash: true}
+	c.Sources = append(c.Sources, dest)
+	sum, err := parse.Read(dest)
+	be.Err(t, err, nil)
+	// the original mock item is the surviving copy
+	c.Add(sum, mock.Item(t, 1))
+	s, err := c.DelDupeFiles()
+	be.Err(t, err, nil)
+	be.True(t, strings.Contains(s, "trashed:"))
+	be.Equal(t, c.Action(dest), dupe.ActTrashed)
+	_, err = os.Stat(filepath.Join(data, "Trash", "files", filepath.Base(dest)))
+	be.Err(t, err, nil)
+}
+
+func TestConfig_RemoveJournal(t *testing.T) {
+	color.Enable = false
+	dest := copyfile(t, 1)
+	keep := mock.Item(t, 1)
+	name := filepath.Join(t.TempDir(), journal.Name)
+	c := dupe.Config{Test: true, Journal: name}
+	c.Sources = append(c.Sources, dest)
+	sum, err := parse.Read(dest)
+	be.Err(t, err, nil)
+	c.Add(sum, keep)
+	_, err = c.DelDupeFiles()
+	be.Err(t, err, nil)
+	be.Equal(t, c.Action(dest), dupe.ActRemoved)
+	entries, err := journal.Read(name)
+	be.Err(t, err, nil)
+	be.Equal(t, len(entries), 1)
+	be.Equal(t, entries[0].Path, dest)
+	be.Equal(t, entries[0].Survivor, keep)
+	be.Equal(t, entries[0].Action, dupe.ActRemoved)
+	n, err := journal.Undo(nil, name)
+	be.Err(t, err, nil)
+	be.Equal(t, n, 1)
+	check, err := parse.Read(dest)
+	be.Err(t, err, nil)
+	be.Equal(t, check, sum)
+}
+
+func TestConfig_DryRun(t *testing.T) {
+	color.Enable = false
+	src, bucket := t.TempDir(), t.TempDir()
+	sub := filepath.Join(src, "sub")
+	err := os.Mkdir(sub, mock.PrivateDir)
+	be.Err(t, err, nil)
+	dup, keep := filepath.Join(sub, "dupe.txt"), filepath.Join(bucket, "keep.txt")
+	for _, name := range []string{dup, keep} {
+		err := os.WriteFile(name, []byte("dry run"), mock.PrivateFile)
+		be.Err(t, err, nil)
+	}
+	sum, err := parse.Read(keep)
+	be.Err(t, err, nil)
+	c := dupe.Config{Test: true, DryRun: true}
+	err = c.SetSource(src)
+	be.Err(t, err, nil)
+	c.Sources = append(c.Sources, dup)
+	c.Add(sum, keep)
+	s, err := c.DelDupeFiles()
+	be.Err(t, err, nil)
+	be.True(t, strings.Contains(s, "would remove: "+dup))
+	be.Equal(t, c.Action(dup), dupe.ActDryRun)
+	var b bytes.Buffer
+	err = c.DelEmptyDirs(&b)
+	be.Err(t, err, nil)
+	be.True(t, strings.Contains(b.String(), "Would remove 1 empty directories"))
+	be.Equal(t, c.Action(sub), dupe.ActDryRun)
+	s = c.DryRunStatus()
+	be.True(t, strings.Contains(s, "2 files and directories, totalling 7 B"))
+	// nothing was changed
+	for _, name := range []string{dup, sub, keep} {
+		_, err = os.Stat(name)
+		be.Err(t, err, nil)
+	}
+}
+
+func TestConfig_Link(t *testing.T) {
+	color.Enable = false
+	err := dupe.CheckLink("soft")
+	be.Err(t, err, dupe.ErrLink)
+	for _, kind := range []string{dupe.LinkHard, dupe.LinkSym} {
+		src, bucket := t.TempDir(), t.TempDir()
+		dup, keep := filepath.Join(src, "dupe.txt"), filepath.Join(bucket, "keep.txt")
+		unique := filepath.Join(src, "unique.txt")
+		for name, data := range map[string]string{dup: "link me", keep: "link me", unique: "unique"} {
+			err := os.WriteFile(name, []byte(data), mock.PrivateFile)
+			be.Err(t, err, nil)
+		}
+		sum, err := parse.Read(keep)
+		be.Err(t, err, nil)
+		link := func() string {
+			c := dupe.Config{Test: true}
+			c.Sources = append(c.Sources, src, dup, unique)
+			c.Add(sum, keep)
+			s, err := c.Link(kind)
+			be.Err(t, err, nil)
+			return s
+		}
+		s := link()
+		be.Equal(t, strings.Count(s, "linked:"), 1)
+		be.True(t, strings.Contains(s, "reclaiming 7 B"))
+		di, err := os.Stat(dup)
+		be.Err(t, err, nil)
+		ki, err := os.Stat(keep)
+		be.Err(t, err, nil)
+		be.True(t, os.SameFile(di, ki))
+		li, err := os.Lstat(dup)
+		be.Err(t, err, nil)
+		be.Equal(t, li.Mode()&fs.ModeSymlink != 0, kind == dupe.LinkSym)
+		// a second run has nothing to link
+		s = link()
+		be.True(t, strings.Contains(s, "No duplicate files to link"))
+	}
+}
+
+// TestConfig_Reflink uses the temporary directory, which often does not support reflinks.
+// Set DUPERS_REFLINK_DIR to a directory on a btrfs or xfs file system, such as a mounted loopback image,
+// to test the sharing of extents.
+func TestConfig_Reflink(t *testing.T) {
+	color.Enable = false
+	dir := os.Getenv("DUPERS_REFLINK_DIR")
+	if dir == "" {
+		dir = t.TempDir()
+	} else {
+		var err error
+		dir, err = os.MkdirTemp(dir, "dupers-reflink")
+		be.Err(t, err, nil)
+		defer os.RemoveAll(dir)
+	}
+	src := filepath.Join(dir, "src")
+	err := os.MkdirAll(src, mock.PrivateDir)
+	be.Err(t, err, nil)
+	data := bytes.Repeat([]byte("reflink "), 64*1024)
+	dup, keep := filepath.Join(src, "dupe.bin"), filepath.Join(dir, "keep.bin")
+	for _, name := range []string{dup, keep} {
+		err := os.WriteFile(name, data, mock.PrivateFile)
+		be.Err(t, err, nil)
+	}
+	sum, err := parse.Read(keep)
+	be.Err(t, err, nil)
+	c := dupe.Config{Test: true}
+	c.Sources = append(c.Sources, src, dup)
+	c.Add(sum, keep)
+	s, err := c.Reflink()
+	if os.Getenv("DUPERS_REFLINK_DIR") == "" && errors.Is(err, dupe.ErrReflink) {
+		// the duplicate is untouched and no temporary files are left behind
+		entries, err := os.ReadDir(src)
+		be.Err(t, err, nil)
+		be.Equal(t, len(entries), 1)
+		check, err := parse.Read(dup)
+		be.Err(t, err, nil)
+		be.Equal(t, check, sum)
+		return
+	}
+	be.Err(t, err, nil)
+	be.True(t, strings.Contains(s, "reflinked:"))
+	be.True(t, strings.Contains(s, "sharing 524 kB"))
+	be.Equal(t, c.Action(dup), dupe.ActReflinked)
+	check, err := parse.Read(dup)
+	be.Err(t, err, nil)
+	be.Equal(t, check, sum)
 }
 
 func TestConfig_Clean(t *testing.T) {
@@ -110,6 +521,191 @@ func TestConfig_WalkDirs(t *testing.T) {
 	be.Err(t, err, nil)
 }
 
+func TestConfig_Dirs(t *testing.T) {
+	color.Enable = false
+	c := dupe.Config{Test: true}
+	_, _, err := c.Dirs(nil)
+	be.Err(t, err)
+	bucket := t.TempDir()
+	for _, dir := range []string{"copy", "original"} {
+		err := os.MkdirAll(filepath.Join(bucket, dir, "sub"), mock.PrivateDir)
+		be.Err(t, err, nil)
+		for name, data := range map[string]string{"a.txt": "whole", filepath.Join("sub", "b.txt"): "directory"} {
+			err := os.WriteFile(filepath.Join(bucket, dir, name), []byte(data), mock.PrivateFile)
+			be.Err(t, err, nil)
+		}
+	}
+	db, path := mock.Database(t)
+	defer db.Close()
+	defer os.Remove(path)
+	err = c.SetBuckets(bucket)
+	be.Err(t, err, nil)
+	err = c.WalkDirs(db)
+	be.Err(t, err, nil)
+	groups, subsets, err := c.Dirs(db)
+	be.Err(t, err, nil)
+	be.Equal(t, len(groups), 1)
+	be.Equal(t, groups[0].Paths, []string{filepath.Join(bucket, "copy"), filepath.Join(bucket, "original")})
+	be.Equal(t, groups[0].Files, 2)
+	be.Equal(t, len(subsets), 0)
+	s := dupe.PrintDirs(groups, subsets)
+	be.True(t, strings.Contains(s, "identical directories, 2 files"))
+}
+
+func TestConfig_Ignore(t *testing.T) {
+	color.Enable = false
+	bucket, src := t.TempDir(), t.TempDir()
+	files := map[string]string{
+		filepath.Join(bucket, "keep.txt"):           "keep",
+		filepath.Join(bucket, ".config", "dot.txt"): "dotfile",
+		filepath.Join(bucket, "build", "app.exe"):   "build",
+		filepath.Join(bucket, "notes.log"):          "log",
+		filepath.Join(bucket, ignore.Name):          "build/\n!.config/\n",
+		filepath.Join(src, "keep.txt"):              "keep",
+		filepath.Join(src, "app.exe"):               "build",
+		filepath.Join(src, "skip", "dot.txt"):       "dotfile",
+		filepath.Join(src, ignore.Name):             "skip/\n",
+	}
+	for name, data := range files {
+		err := os.MkdirAll(filepath.Dir(name), mock.PrivateDir)
+		be.Err(t, err, nil)
+		err = os.WriteFile(name, []byte(data), mock.PrivateFile)
+		be.Err(t, err, nil)
+	}
+	db, path := mock.Database(t)
+	defer db.Close()
+	defer os.Remove(path)
+	c := dupe.Config{Test: true, Exclude: []string{"*.log"}}
+	err := c.SetBuckets(bucket)
+	be.Err(t, err, nil)
+	err = c.WalkDirs(db)
+	be.Err(t, err, nil)
+	records, err := database.ListRecords(db, bucket)
+	be.Err(t, err, nil)
+	be.Equal(t, len(records), 2)
+	for _, name := range []string{"keep.txt", filepath.Join(".config", "dot.txt")} {
+		_, ok := records[database.Filepath(filepath.Join(bucket, name))]
+		be.True(t, ok)
+	}
+	err = c.SetSource(src)
+	be.Err(t, err, nil)
+	err = c.WalkSource()
+	be.Err(t, err, nil)
+	s, err := c.Print()
+	be.Err(t, err, nil)
+	be.True(t, strings.Contains(s, filepath.Join(src, "keep.txt")))
+	be.True(t, !strings.Contains(s, filepath.Join(src, "skip")))
+	// a stored item that is now excluded is not matched
+	c = dupe.Config{Test: true, Scanner: c.Scanner, Exclude: []string{"keep.txt"}}
+	s, err = c.Print()
+	be.Err(t, err, nil)
+	be.True(t, strings.Contains(s, "No duplicate files found"))
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int64
+	}{
+		{"", 0},
+		{"500", 500},
+		{"4KiB", 4096},
+		{"2 GB", 2000000000},
+	}
+	for _, tt := range tests {
+		got, err := dupe.ParseSize(tt.s)
+		be.Err(t, err, nil)
+		be.Equal(t, got, tt.want)
+	}
+	for _, s := range []string{"-1", "4XB", "99999EiB"} {
+		_, err := dupe.ParseSize(s)
+		be.Err(t, err, dupe.ErrSize)
+	}
+	c := dupe.Config{MinSize: 10, MaxSize: 5}
+	be.Err(t, c.CheckSize(), dupe.ErrSizes)
+}
+
+func TestConfig_Size(t *testing.T) {
+	color.Enable = false
+	bucket, src := t.TempDir(), t.TempDir()
+	files := map[string]string{
+		filepath.Join(bucket, "empty"): "",
+		filepath.Join(bucket, "tiny"):  "1",
+		filepath.Join(bucket, "large"): "size filters",
+		filepath.Join(src, "empty"):    "",
+		filepath.Join(src, "tiny"):     "1",
+		filepath.Join(src, "large"):    "size filters",
+	}
+	for name, data := range files {
+		err := os.WriteFile(name, []byte(data), mock.PrivateFile)
+		be.Err(t, err, nil)
+	}
+	db, path := mock.Database(t)
+	defer db.Close()
+	defer os.Remove(path)
+	c := dupe.Config{Test: true, MinSize: 2}
+	err := c.SetBuckets(bucket)
+	be.Err(t, err, nil)
+	err = c.WalkDirs(db)
+	be.Err(t, err, nil)
+	records, err := database.ListRecords(db, bucket)
+	be.Err(t, err, nil)
+	be.Equal(t, len(records), 1)
+	be.True(t, strings.Contains(c.Status(), "skipped 1 empty file, 1 file smaller than 2 B"))
+	err = c.Checksum(db, filepath.Join(bucket, "empty"), bucket)
+	be.Err(t, err, dupe.ErrFileEmpty)
+	// the empty option includes the zero byte files
+	c = dupe.Config{Test: true, Empty: true, MaxSize: 1}
+	err = c.SetBuckets(bucket)
+	be.Err(t, err, nil)
+	err = c.WalkDirs(db)
+	be.Err(t, err, nil)
+	err = c.SetSource(src)
+	be.Err(t, err, nil)
+	err = c.WalkSource()
+	be.Err(t, err, nil)
+	be.Equal(t, len(c.Sources), 3)
+	s, err := c.Print()
+	be.Err(t, err, nil)
+	be.True(t, strings.Contains(s, filepath.Join(src, "empty")))
+	be.True(t, !strings.Contains(s, filepath.Join(src, "large")))
+}
+
+func TestConfig_Options(t *testing.T) {
+	bucket := t.TempDir()
+	err := os.WriteFile(filepath.Join(bucket, "a.txt"), []byte("options"), mock.PrivateFile)
+	be.Err(t, err, nil)
+	db, path := mock.Database(t)
+	defer db.Close()
+	defer os.Remove(path)
+	c := dupe.Config{Test: true}
+	err = c.SetBuckets(bucket)
+	be.Err(t, err, nil)
+	err = c.WalkDirs(db)
+	be.Err(t, err, nil)
+	records, err := database.ListRecords(db, bucket)
+	be.Err(t, err, nil)
+	be.Equal(t, len(records), 1)
+	// read-only buckets keep the stored checksums and are not walked
+	err = os.WriteFile(filepath.Join(bucket, "b.txt"), []byte("read-only"), mock.PrivateFile)
+	be.Err(t, err, nil)
+	c.Options = map[string]dupe.Options{bucket: {ReadOnly: true}}
+	be.Err(t, c.ReadOnly(bucket), dupe.ErrReadOnly)
+	be.Equal(t, len(c.Writable(bucket, mock.NoSuchFile)), 1)
+	err = c.WalkDirs(db)
+	be.Err(t, err, nil)
+	records, err = database.ListRecords(db, bucket)
+	be.Err(t, err, nil)
+	be.Equal(t, len(records), 1)
+	c.Options = nil
+	be.Err(t, c.ReadOnly(bucket), nil)
+	err = c.WalkDirs(db)
+	be.Err(t, err, nil)
+	records, err = database.ListRecords(db, bucket)
+	be.Err(t, err, nil)
+	be.Equal(t, len(records), 2)
+}
+
 func TestConfig_WalkDir(t *testing.T) {
 	c := dupe.Config{Test: true, Debug: false}
 	bucket1, err := mock.Bucket(t, 1)
@@ -126,6 +722,100 @@ func TestConfig_WalkDir(t *testing.T) {
 	be.Err(t, err, nil)
 }
 
+func TestConfig_WalkDirWorkers(t *testing.T) {
+	bucket1, err := mock.Bucket(t, 1)
+	be.Err(t, err, nil)
+	for _, workers := range []int{0, 1, 4} {
+		c := dupe.Config{Test: true, Workers: workers}
+		db, path := mock.Database(t)
+		err = c.WalkDir(db, parse.Bucket(bucket1))
+		be.Err(t, err, nil)
+		const filesCount = 24
+		be.Equal(t, c.Files, filesCount)
+		list, err := database.List(db, bucket1)
+		be.Err(t, err, nil)
+		be.True(t, len(list) >= filesCount)
+		db.Close()
+		os.Remove(path)
+	}
+}
+
+func TestConfig_WalkDirRescan(t *testing.T) {
+	c := dupe.Config{Test: true, Quiet: true}
+	db, path := mock.Database(t)
+	defer db.Close()
+	defer os.Remove(path)
+	root := t.TempDir()
+	name := filepath.Join(root, "rescan.txt")
+	err := os.WriteFile(name, []byte("original content"), mock.PrivateFile)
+	be.Err(t, err, nil)
+	err = c.WalkDir(db, parse.Bucket(root))
+	be.Err(t, err, nil)
+	list, err := database.List(db, root)
+	be.Err(t, err, nil)
+	sum, err := parse.Read(name)
+	be.Err(t, err, nil)
+	be.Equal(t, list[database.Filepath(name)], sum)
+	// an edited file is hashed again
+	err = os.WriteFile(name, []byte("edited content that is longer"), mock.PrivateFile)
+	be.Err(t, err, nil)
+	err = c.WalkDir(db, parse.Bucket(root))
+	be.Err(t, err, nil)
+	list, err = database.List(db, root)
+	be.Err(t, err, nil)
+	sum, err = parse.Read(name)
+	be.Err(t, err, nil)
+	be.Equal(t, list[database.Filepath(name)], sum)
+	// a renamed file keeps its checksum and the stale item is removed
+	renamed := filepath.Join(root, "renamed.txt")
+	err = os.Rename(name, renamed)
+	be.Err(t, err, nil)
+	err = c.WalkDir(db, parse.Bucket(root))
+	be.Err(t, err, nil)
+	list, err = database.List(db, root)
+	be.Err(t, err, nil)
+	be.Equal(t, list[database.Filepath(renamed)], sum)
+	_, ok := list[database.Filepath(name)]
+	be.True(t, !ok)
+}
+
+func TestConfig_Self(t *testing.T) {
+	tmp := t.TempDir()
+	write := func(name string, b []byte) {
+		t.Helper()
+		dir := filepath.Join(tmp, filepath.Dir(name))
+		err := os.MkdirAll(dir, mock.PrivateDir)
+		be.Err(t, err, nil)
+		err = os.WriteFile(filepath.Join(tmp, name), b, mock.PrivateFile)
+		be.Err(t, err, nil)
+	}
+	large := make([]byte, parse.PartSize*3)
+	write("a.txt", []byte("identical content"))
+	write(filepath.Join("sub", "b.txt"), []byte("identical content"))
+	write("c.txt", []byte("unique content!!!"))
+	write("empty1", nil)
+	write("empty2", nil)
+	write("large1", large)
+	write(filepath.Join("sub", "large2"), large)
+	large[parse.PartSize+1] = 'x'
+	write("large3", large)
+	c := dupe.Config{Test: true}
+	err := c.SetSource(tmp)
+	be.Err(t, err, nil)
+	err = c.WalkSource()
+	be.Err(t, err, nil)
+	groups, err := c.Self()
+	be.Err(t, err, nil)
+	be.Equal(t, len(groups), 2)
+	be.Equal(t, groups[0].Paths, []string{filepath.Join(tmp, "a.txt"), filepath.Join(tmp, "sub", "b.txt")})
+	be.Equal(t, groups[1].Paths, []string{filepath.Join(tmp, "large1"), filepath.Join(tmp, "sub", "large2")})
+	be.Equal(t, groups[1].Size, int64(len(large)))
+	s := dupe.PrintSelf(groups...)
+	be.Equal(t, strings.Count(s, "⤷"), 2)
+	s = dupe.PrintSelf()
+	be.True(t, strings.Contains(s, "No duplicate files found"))
+}
+
 func TestConfig_WalkSource(t *testing.T) {
 	c := dupe.Config{}
 	bucket2, err := mock.Bucket(t, 2)
@@ -195,6 +885,17 @@ func TestChecksum(t *testing.T) {
 	be.Err(t, err)
 	err = c.Checksum(db, item, bucket1)
 	be.Err(t, err, nil)
+	records, err := database.ListRecords(db, bucket1)
+	be.Err(t, err, nil)
+	r, ok := records[database.Filepath(item)]
+	be.True(t, ok)
+	be.True(t, r.Stat())
+	be.True(t, mock.Sum(t, 1, r.Sum))
+	// legacy values are still readable
+	list, err := database.List(db, bucket1)
+	be.Err(t, err, nil)
+	be.True(t, mock.Sum(t, 0, list[database.Filepath(mock.Item(t, 0))]))
+	be.True(t, mock.Sum(t, 1, list[database.Filepath(item)]))
 }
 
 func TestConfig_WalkArchiver(t *testing.T) {
@@ -255,6 +956,96 @@ func TestMatch(t *testing.T) {
 	s = dupe.Match(tmpDir, item1)
 	ok = strings.Contains(s, item1)
 	be.True(t, ok)
+	item2 := mock.Item(t, 2)
+	s = dupe.Match(tmpDir, item1, item2)
+	ok = strings.Contains(s, item1) && strings.Contains(s, item2)
+	be.True(t, ok)
+	be.Equal(t, strings.Count(s, "⤷"), 2)
+}
+
+func TestConfig_Results(t *testing.T) {
+	color.Enable = false
+	item1 := mock.Item(t, 1)
+	dest := copyfile(t, 1)
+	c := dupe.Config{Test: true}
+	err := c.SetBuckets(filepath.Dir(item1))
+	be.Err(t, err, nil)
+	sum, err := parse.Read(item1)
+	be.Err(t, err, nil)
+	c.Add(sum, item1)
+	c.Sources = []string{dest, mock.Item(t, 2)}
+	results, err := c.Results()
+	be.Err(t, err, nil)
+	be.Equal(t, len(results), 1)
+	r := results[0]
+	be.Equal(t, r.Source, dest)
+	be.Equal(t, r.Matches, []string{item1})
+	be.Equal(t, r.Bucket, filepath.Dir(item1))
+	be.Equal(t, r.Checksum, mock.ItemSum(t, 1))
+	be.Equal(t, r.Size, int64(20))
+	be.Equal(t, r.Action, "")
+	_, err = c.DelDupeFiles()
+	be.Err(t, err, nil)
+	c.SetActions(results...)
+	be.Equal(t, results[0].Action, dupe.ActRemoved)
+}
+
+func TestWriteResults(t *testing.T) {
+	results := []dupe.Result{
+		{Source: "a", Matches: []string{"b", "c"}, Bucket: "d", Checksum: "ef", Size: 1},
+		{Source: "g", Matches: []string{"h"}, Checksum: "ij", Size: 2, Action: dupe.ActRemoved},
+	}
+	err := dupe.CheckFormat("xml")
+	be.Err(t, err, dupe.ErrFormat)
+	err = dupe.CheckFormat("JSON")
+	be.Err(t, err, nil)
+	var w bytes.Buffer
+	err = dupe.WriteResults(&w, "xml", results...)
+	be.Err(t, err, dupe.ErrFormat)
+	err = dupe.WriteResults(&w, dupe.JSON, results...)
+	be.Err(t, err, nil)
+	var decoded []dupe.Result
+	err = json.Unmarshal(w.Bytes(), &decoded)
+	be.Err(t, err, nil)
+	be.Equal(t, decoded, results)
+	w.Reset()
+	err = dupe.WriteResults(&w, dupe.JSON)
+	be.Err(t, err, nil)
+	be.Equal(t, strings.TrimSpace(w.String()), "[]")
+	w.Reset()
+	err = dupe.WriteResults(&w, dupe.NDJSON, results...)
+	be.Err(t, err, nil)
+	be.Equal(t, strings.Count(w.String(), "\n"), 2)
+	be.True(t, strings.Contains(w.String(), `"action":"removed"`))
+	w.Reset()
+	err = dupe.WriteResults(&w, dupe.CSV, results...)
+	be.Err(t, err, nil)
+	rows, err := csv.NewReader(&w).ReadAll()
+	be.Err(t, err, nil)
+	be.Equal(t, len(rows), 4)
+	be.Equal(t, rows[0], []string{"source", "match", "bucket", "checksum", "size", "action"})
+	be.Equal(t, rows[3], []string{"g", "h", "", "ij", "2", "removed"})
+}
+
+func TestSearchResults(t *testing.T) {
+	_, err := dupe.SearchResults(nil, nil)
+	be.Err(t, err)
+	db, path := mock.Database(t)
+	defer db.Close()
+	defer os.Remove(path)
+	results, err := dupe.SearchResults(db, nil)
+	be.Err(t, err, nil)
+	be.Equal(t, len(results), 0)
+	bucket1, err := mock.Bucket(t, 1)
+	be.Err(t, err, nil)
+	item1 := mock.Item(t, 1)
+	m := database.Matches{database.Filepath(item1): database.Bucket(bucket1)}
+	results, err = dupe.SearchResults(db, &m)
+	be.Err(t, err, nil)
+	be.Equal(t, len(results), 1)
+	be.Equal(t, results[0].Matches, []string{item1})
+	be.Equal(t, results[0].Bucket, bucket1)
+	be.Equal(t, results[0].Checksum, mock.ItemSum(t, 1))
 }
 
 func TestSkipDir(t *testing.T) {
@@ -285,3 +1076,55 @@ func TestSkipFile(t *testing.T) {
 		be.True(t, b)
 	}
 }
+
+func TestCompareSum(t *testing.T) {
+	db, path := mock.Database(t)
+	defer db.Close()
+	defer os.Remove(path)
+	item1 := mock.Item(t, 1)
+	sum, err := parse.Read(item1)
+	be.Err(t, err, nil)
+	m, err := database.CompareSum(db, sum)
+	be.Err(t, err, nil)
+	be.Equal(t, len(*m), 1)
+	_, ok := (*m)[database.Filepath(item1)]
+	be.True(t, ok)
+	m, err = database.CompareSum(db, [32]byte{})
+	be.Err(t, err, nil)
+	be.Equal(t, len(*m), 0)
+	_, err = database.CompareSum(nil, sum)
+	be.Err(t, err)
+}
+
+func TestRebuild(t *testing.T) {
+	db, path := mock.Database(t)
+	defer db.Close()
+	defer os.Remove(path)
+	_, err := database.Rebuild(nil)
+	be.Err(t, err)
+	n, err := database.Rebuild(db)
+	be.Err(t, err, nil)
+	be.Equal(t, n, 3)
+	all, err := database.All(db)
+	be.Err(t, err, nil)
+	be.Equal(t, len(all), 2)
+
+	item1 := mock.Item(t, 1)
+	term := strings.ToUpper(filepath.Base(item1))
+	m, err := database.CompareNoCase(db, term)
+	be.Err(t, err, nil)
+	be.Equal(t, len(*m), 1)
+	_, ok := (*m)[database.Filepath(item1)]
+	be.True(t, ok)
+	m, err = database.Compare(db, term)
+	be.Err(t, err, nil)
+	be.Equal(t, len(*m), 0)
+
+	bucket1, err := mock.Bucket(t, 1)
+	be.Err(t, err, nil)
+	err = database.Remove(db, bucket1)
+	be.Err(t, err, nil)
+	n, err = database.Rebuild(db)
+	be.Err(t, err, nil)
+	be.Equal(t, n, 0)
+}
@@ -0,0 +1,186 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+package database
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bengarrett/dupers/pkg/database/record"
+	"github.com/h2non/filetype"
+	bolt "go.etcd.io/bbolt"
+	bberr "go.etcd.io/bbolt/errors"
+)
+
+// Kinds of files that are detected from their content.
+const (
+	Archive  = "archive"
+	Audio    = "audio"
+	Document = "document"
+	Image    = "image"
+	Video    = "video"
+)
+
+// header is the number of bytes read from the start of a file to detect its kind.
+const header = 8192
+
+var ErrKind = errors.New("file type must be either image, audio, video, archive or document")
+
+// Filter the stored files using their extension, size, modification time and the kind of content.
+// The size and modification time are stored in the bucket items,
+// while the items without any file stat data and the kind of content use the files on the host system.
+type Filter struct {
+	Exts    []string  // Exts are the file extensions to keep, such as .jpg.
+	Kind    string    // Kind of content to keep, either image, audio, video, archive or document.
+	Larger  int64     // Larger keeps the files larger than this number of bytes.
+	Smaller int64     // Smaller keeps the files smaller than this number of bytes.
+	Newer   time.Time // Newer keeps the files modified after this time.
+	Older   time.Time // Older keeps the files modified before this time.
+}
+
+// Kinds returns the kinds of files that can be detected.
+func Kinds() []string {
+	return []string{Image, Audio, Video, Archive, Document}
+}
+
+// Check returns an error if the kind of the filter cannot be detected.
+func (f *Filter) Check() error {
+	if f == nil || f.Kind == "" {
+		return nil
+	}
+	if !slices.Contains(Kinds(), strings.ToLower(f.Kind)) {
+		return fmt.Errorf("%w: %s", ErrKind, f.Kind)
+	}
+	return nil
+}
+
+// Empty returns true when the filter keeps every file.
+func (f *Filter) Empty() bool {
+	return f == nil || (len(f.Exts) == 0 && f.Kind == "" && f.Larger == 0 && f.Smaller == 0 &&
+		f.Newer.IsZero() && f.Older.IsZero())
+}
+
+// Match returns true when the filter keeps the named file using the stored bucket item value.
+func (f *Filter) Match(name string, value []byte) bool {
+	if f.Empty() {
+		return true
+	}
+	if !f.ext(name) {
+		return false
+	}
+	if f.Larger != 0 || f.Smaller != 0 || !f.Newer.IsZero() || !f.Older.IsZero() {
+		r, err := record.Decode(value)
+		if err != nil {
+			return false
+		}
+		if !r.Stat() {
+			info, err := os.Stat(name)
+			if err != nil {
+				return false
+			}
+			r = record.New(r.Sum, info)
+		}
+		if !f.stat(r) {
+			return false
+		}
+	}
+	if f.Kind == "" {
+		return true
+	}
+	return Kind(name) == strings.ToLower(f.Kind)
+}
+
+// ext returns true when the named file uses one of the extensions.
+func (f *Filter) ext(name string) bool {
+	if len(f.Exts) == 0 {
+		return true
+	}
+	ext := filepath.Ext(name)
+	for _, s := range f.Exts {
+		if strings.EqualFold(ext, "."+strings.TrimPrefix(s, ".")) {
+			return true
+		}
+	}
+	return false
+}
+
+// stat returns true when the size and modification time of the record are within the filter.
+func (f *Filter) stat(r record.Record) bool {
+	switch {
+	case f.Larger != 0 && r.Size <= f.Larger,
+		f.Smaller != 0 && r.Size >= f.Smaller,
+		!f.Newer.IsZero() && !r.ModTime.After(f.Newer),
+		!f.Older.IsZero() && !r.ModTime.Before(f.Older):
+		return false
+	}
+	return true
+}
+
+// Kind returns the kind of content of the named file, or an empty string when it is unknown.
+func Kind(name string) string {
+	file, err := os.Open(name)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	buf := make([]byte, header)
+	n, err := io.ReadFull(file, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return ""
+	}
+	buf = buf[:n]
+	switch {
+	case filetype.IsImage(buf):
+		return Image
+	case filetype.IsAudio(buf):
+		return Audio
+	case filetype.IsVideo(buf):
+		return Video
+	case filetype.IsArchive(buf):
+		return Archive
+	case filetype.IsDocument(buf):
+		return Document
+	}
+	return ""
+}
+
+// Filter removes the matches that are not kept by the filter.
+func (m Matches) Filter(db *bolt.DB, f *Filter) error {
+	if f.Empty() {
+		return nil
+	}
+	if db == nil {
+		return bberr.ErrDatabaseNotOpen
+	}
+	return db.View(func(tx *bolt.Tx) error {
+		for path, bucket := range m {
+			abs, err := AbsB(string(bucket))
+			if err != nil {
+				return err
+			}
+			b := tx.Bucket(abs)
+			if b == nil {
+				return fmt.Errorf("%w: '%s'", bberr.ErrBucketNotFound, bucket)
+			}
+			if !f.Match(string(path), b.Get([]byte(path))) {
+				delete(m, path)
+			}
+		}
+		return nil
+	})
+}
+
+// CompareFilter finds the stored files that are kept by the filter.
+func CompareFilter(db *bolt.DB, f *Filter, buckets ...string) (*Matches, error) {
+	if f.Empty() {
+		return nil, ErrNoTerm
+	}
+	return search(db, nil, func(key, value []byte) bool {
+		return f.Match(string(key), value)
+	}, buckets...)
+}
@@ -0,0 +1,74 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+package database_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bengarrett/dupers/internal/mock"
+	"github.com/bengarrett/dupers/pkg/database"
+	"github.com/bengarrett/dupers/pkg/database/record"
+	"github.com/nalgeon/be"
+)
+
+func TestFilter_Check(t *testing.T) {
+	var f *database.Filter
+	be.Err(t, f.Check(), nil)
+	be.True(t, f.Empty())
+	f = &database.Filter{Kind: "Video"}
+	be.Err(t, f.Check(), nil)
+	be.True(t, !f.Empty())
+	f = &database.Filter{Kind: "spreadsheet"}
+	be.Err(t, f.Check(), database.ErrKind)
+}
+
+func TestFilter_Match(t *testing.T) {
+	const name = "/home/user/video.MKV"
+	mod := time.Date(2014, 6, 1, 0, 0, 0, 0, time.UTC)
+	value := record.Record{Size: 2 << 30, ModTime: mod}.Bytes()
+
+	f := &database.Filter{Exts: []string{"mp4", ".mkv"}}
+	be.True(t, f.Match(name, value))
+	f = &database.Filter{Exts: []string{"mp4"}}
+	be.True(t, !f.Match(name, value))
+
+	f = &database.Filter{Larger: 1 << 30, Older: time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)}
+	be.True(t, f.Match(name, value))
+	f = &database.Filter{Smaller: 1 << 30}
+	be.True(t, !f.Match(name, value))
+	f = &database.Filter{Newer: time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)}
+	be.True(t, !f.Match(name, value))
+
+	// a legacy item without any file stat data uses the file on the host system
+	tmp := filepath.Join(t.TempDir(), "legacy.txt")
+	be.Err(t, os.WriteFile(tmp, []byte("hello"), mock.PrivateFile), nil)
+	legacy := record.New([32]byte{}, nil).Bytes()
+	f = &database.Filter{Smaller: 10}
+	be.True(t, f.Match(tmp, legacy))
+	f = &database.Filter{Larger: 10}
+	be.True(t, !f.Match(tmp, legacy))
+	be.True(t, !f.Match("/this/file/does/not/exist", legacy))
+}
+
+func TestCompareFilter(t *testing.T) {
+	db, path := mock.Database(t)
+	defer db.Close()
+	defer os.Remove(path)
+	_, err := database.CompareFilter(db, nil)
+	be.Err(t, err, database.ErrNoTerm)
+	f := &database.Filter{Larger: 1}
+	m, err := database.CompareFilter(db, f)
+	be.Err(t, err, nil)
+	be.Equal(t, len(*m), 3)
+
+	item := mock.Item(t, 1)
+	zip := filepath.Join(filepath.Dir(item), "randomfiles.zip")
+	be.Equal(t, database.Kind(zip), database.Archive)
+	be.Equal(t, database.Kind(item), "")
+	m = &database.Matches{database.Filepath(item): database.Bucket(filepath.Dir(item))}
+	err = m.Filter(db, &database.Filter{Kind: database.Archive})
+	be.Err(t, err, nil)
+	be.Equal(t, len(*m), 0)
+}
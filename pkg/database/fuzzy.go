@@ -0,0 +1,168 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+package database
+
+import (
+	"cmp"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Rank is a stored file that resembles the fuzzy search term.
+type Rank struct {
+	Path   Filepath // Path is the absolute path of the stored file.
+	Bucket Bucket   // Bucket the file was sourced from.
+	Score  int      // Score is the number of edits for the term to appear in the path, lower is closer.
+	Span   int      // Span is the length of the shortest part of the filename with the letters of the term in order, or 0.
+}
+
+// Ranks are the stored files sorted from the closest to the furthest match.
+type Ranks []Rank
+
+// Fuzzy returns the rank of the named path compared with the term, and true when they resemble each other.
+// The score is the fewest single letter insertions, deletions or substitutions to make the term
+// appear within the path, so a score of 0 means the path contains the term.
+// The path resembles the term when the score is no more than a third of the term length,
+// or when the letters of the term are all in the filename in the same order, such as rcpt in receipt.pdf.
+// The term is case insensitive unless exact is true, and it only uses the filename when base is true.
+func Fuzzy(term, name string, exact, base bool) (Rank, bool) {
+	r := Rank{Path: Filepath(name)}
+	if term == "" {
+		return r, false
+	}
+	file := filepath.Base(name)
+	if !exact {
+		term, name, file = strings.ToLower(term), strings.ToLower(name), strings.ToLower(file)
+	}
+	s := name
+	if base {
+		s = file
+	}
+	r.Score = distance([]rune(term), []rune(s))
+	r.Span = span([]rune(term), []rune(file))
+	return r, r.Span > 0 || r.Score <= utf8.RuneCountInString(term)/3
+}
+
+// distance returns the edit distance between the term and its closest substring of s.
+func distance(term, s []rune) int {
+	// the previous row holds the distance of each term prefix, where the matched substring can begin anywhere
+	prev, row := make([]int, len(term)+1), make([]int, len(term)+1)
+	for i := range prev {
+		prev[i] = i
+	}
+	best := prev[len(term)]
+	for _, r := range s {
+		row[0] = 0
+		for i, t := range term {
+			cost := 1
+			if t == r {
+				cost = 0
+			}
+			row[i+1] = min(prev[i]+cost, prev[i+1]+1, row[i]+1)
+		}
+		best = min(best, row[len(term)])
+		prev, row = row, prev
+	}
+	return best
+}
+
+// span returns the length of the shortest part of s that has every letter of the term in the same order,
+// or 0 when s does not have them.
+func span(term, s []rune) int {
+	best := 0
+	for start := range s {
+		if s[start] != term[0] {
+			continue
+		}
+		i := 1
+		end := start + 1
+		for ; end < len(s) && i < len(term); end++ {
+			if s[end] == term[i] {
+				i++
+			}
+		}
+		if i < len(term) {
+			// the later starts cannot find the letters either
+			break
+		}
+		if n := end - start; best == 0 || n < best {
+			best = n
+		}
+	}
+	return best
+}
+
+// Sort the ranks by score, then by the shortest span of the letters in order,
+// the shortest filename and the path.
+func (r Ranks) Sort() {
+	slices.SortFunc(r, func(a, b Rank) int {
+		if n := cmp.Compare(a.Score, b.Score); n != 0 {
+			return n
+		}
+		if a.Span != b.Span {
+			// a filename without the letters in order is the furthest match
+			switch {
+			case a.Span == 0:
+				return 1
+			case b.Span == 0:
+				return -1
+			}
+			return cmp.Compare(a.Span, b.Span)
+		}
+		if n := cmp.Compare(len(filepath.Base(string(a.Path))), len(filepath.Base(string(b.Path)))); n != 0 {
+			return n
+		}
+		return strings.Compare(string(a.Path), string(b.Path))
+	})
+}
+
+// Matches returns the ranks as a collection of matches.
+func (r Ranks) Matches() *Matches {
+	m := make(Matches, len(r))
+	for _, rank := range r {
+		m[rank.Path] = rank.Bucket
+	}
+	return &m
+}
+
+// Keep returns the ranks that are in the matches, keeping their order.
+func (r Ranks) Keep(m *Matches) Ranks {
+	if m == nil {
+		return Ranks{}
+	}
+	return slices.DeleteFunc(r, func(rank Rank) bool {
+		_, ok := (*m)[rank.Path]
+		return !ok
+	})
+}
+
+// CompareFuzzy finds the stored files that resemble the term, sorted from the closest match.
+// The term is case insensitive unless exact is true, and it only uses the filename when base is true.
+// The trigram index cannot be used as a misspelled term has different trigrams, so every item is read.
+func CompareFuzzy(db *bolt.DB, term string, exact, base bool, buckets ...string) (Ranks, error) {
+	if term == "" {
+		return nil, ErrNoTerm
+	}
+	found := map[Filepath]Rank{}
+	m, err := search(db, nil, func(key, _ []byte) bool {
+		r, ok := Fuzzy(term, string(key), exact, base)
+		if ok {
+			found[r.Path] = r
+		}
+		return ok
+	}, buckets...)
+	if err != nil {
+		return nil, err
+	}
+	ranks := make(Ranks, 0, len(*m))
+	for path, bucket := range *m {
+		r := found[path]
+		r.Bucket = bucket
+		ranks = append(ranks, r)
+	}
+	ranks.Sort()
+	return ranks, nil
+}
@@ -0,0 +1,88 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+package database_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/bengarrett/dupers/internal/mock"
+	"github.com/bengarrett/dupers/pkg/database"
+	"github.com/nalgeon/be"
+)
+
+func TestFuzzy(t *testing.T) {
+	const name = "/home/user/receipt.pdf"
+	_, ok := database.Fuzzy("", name, false, false)
+	be.True(t, !ok)
+	r, ok := database.Fuzzy("recipt", name, false, false)
+	be.True(t, ok)
+	be.Equal(t, r.Score, 1)
+	be.Equal(t, r.Span, 7)
+	r, ok = database.Fuzzy("RECEIPT", name, false, true)
+	be.True(t, ok)
+	be.Equal(t, r.Score, 0)
+	be.Equal(t, r.Path, database.Filepath(name))
+	_, ok = database.Fuzzy("RECEIPT", name, true, true)
+	be.True(t, !ok)
+	// the letters are in order but too far apart for the edit distance
+	r, ok = database.Fuzzy("rcpt", name, false, true)
+	be.True(t, ok)
+	be.True(t, r.Score > 1)
+	r, ok = database.Fuzzy("recipt", "/home/user/recipe.pdf", false, true)
+	be.True(t, ok)
+	be.Equal(t, r.Span, 0)
+	r, ok = database.Fuzzy("recipt", "/home/user/recipe-cake.txt", false, true)
+	be.True(t, ok)
+	be.Equal(t, r.Span, 13)
+	_, ok = database.Fuzzy("invoice", name, false, false)
+	be.True(t, !ok)
+	// the directories are only used without base
+	_, ok = database.Fuzzy("user", name, false, true)
+	be.True(t, !ok)
+	r, ok = database.Fuzzy("user", name, false, false)
+	be.True(t, ok)
+	be.Equal(t, r.Score, 0)
+}
+
+func TestRanks_Sort(t *testing.T) {
+	r := database.Ranks{
+		{Path: "/b/receipts.pdf", Score: 1, Span: 7},
+		{Path: "/c/recipe.pdf", Score: 1},
+		{Path: "/d/recipe-cake.txt", Score: 1, Span: 13},
+		{Path: "/a/receipt.pdf", Score: 1, Span: 7},
+		{Path: "/z/receipt.pdf", Score: 0, Span: 7},
+	}
+	r.Sort()
+	be.Equal(t, r[0].Path, database.Filepath("/z/receipt.pdf"))
+	be.Equal(t, r[1].Path, database.Filepath("/a/receipt.pdf"))
+	be.Equal(t, r[2].Path, database.Filepath("/b/receipts.pdf"))
+	be.Equal(t, r[3].Path, database.Filepath("/d/recipe-cake.txt"))
+	be.Equal(t, r[4].Path, database.Filepath("/c/recipe.pdf"))
+	m := r.Matches()
+	be.Equal(t, len(*m), 5)
+	delete(*m, "/a/receipt.pdf")
+	r = r.Keep(m)
+	be.Equal(t, len(r), 4)
+	be.Equal(t, r[1].Path, database.Filepath("/b/receipts.pdf"))
+}
+
+func TestCompareFuzzy(t *testing.T) {
+	db, path := mock.Database(t)
+	defer db.Close()
+	defer os.Remove(path)
+	_, err := database.CompareFuzzy(db, "", false, true)
+	be.Err(t, err, database.ErrNoTerm)
+	// item 1 is named 3a9dnxgSVEnJ
+	item1 := mock.Item(t, 1)
+	r, err := database.CompareFuzzy(db, "3A9DMXG", false, true)
+	be.Err(t, err, nil)
+	be.Equal(t, len(r), 1)
+	be.Equal(t, r[0].Path, database.Filepath(item1))
+	be.Equal(t, r[0].Score, 1)
+	r, err = database.CompareFuzzy(db, "3A9DMXG", true, true)
+	be.Err(t, err, nil)
+	be.Equal(t, len(r), 0)
+	r, err = database.CompareFuzzy(db, "a", false, true)
+	be.Err(t, err, nil)
+	be.Equal(t, len(r), 2)
+}
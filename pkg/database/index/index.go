@@ -0,0 +1,384 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+
+// Package index provides the trigram index of the stored paths, which narrows the candidates of the filename searches.
+//
+// The index of each bucket is kept in a nested bucket of the top-level index bucket.
+// It assigns every path a numeric id and lists the ids of the paths that contain each
+// lowercase trigram, a sequence of three bytes, together with the number of paths that use it.
+// The ids of a trigram are split into blocks of 4096, each stored as a sorted list of offsets.
+// A bucket without an index is searched by reading every item.
+package index
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"slices"
+
+	bolt "go.etcd.io/bbolt"
+	bberr "go.etcd.io/bbolt/errors"
+)
+
+// Name of the top-level bucket that holds the index of every bucket.
+// The stored buckets are absolute directory paths, so they cannot use this name.
+const Name = "dupers:index"
+
+// N is the number of bytes in each gram.
+const N = 3
+
+const (
+	block   = 4096 // block is the number of ids in each list of offsets.
+	idLen   = 8    // idLen is the byte length of an id.
+	postLen = 2    // postLen is the byte length of an offset within a block.
+)
+
+var (
+	ids    = []byte("ids")    // ids bucket maps each path to its id.
+	paths  = []byte("paths")  // paths bucket maps each id to its path.
+	grams  = []byte("grams")  // grams bucket maps each trigram and block to the offsets of the ids.
+	counts = []byte("counts") // counts bucket has the number of paths that use each trigram.
+)
+
+// Grams returns the unique, lowercase trigrams of the string.
+// A string shorter than three bytes has no trigrams.
+func Grams(s []byte) [][]byte {
+	s = bytes.ToLower(s)
+	if len(s) < N {
+		return nil
+	}
+	seen := make(map[string]bool, len(s))
+	gs := make([][]byte, 0, len(s)-N+1)
+	for i := 0; i+N <= len(s); i++ {
+		g := s[i : i+N]
+		if seen[string(g)] {
+			continue
+		}
+		seen[string(g)] = true
+		gs = append(gs, g)
+	}
+	return gs
+}
+
+// Exists returns true when the named bucket has an index.
+func Exists(tx *bolt.Tx, bucket string) bool {
+	return lookup(tx, bucket) != nil
+}
+
+// Create an empty index for the named bucket, which should be a new, empty bucket.
+// An existing index is kept.
+func Create(tx *bolt.Tx, bucket string) error {
+	root, err := tx.CreateBucketIfNotExists([]byte(Name))
+	if err != nil {
+		return err
+	}
+	b, err := root.CreateBucketIfNotExists([]byte(bucket))
+	if err != nil {
+		return err
+	}
+	for _, name := range [][]byte{ids, paths, grams, counts} {
+		if _, err := b.CreateBucketIfNotExists(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Build a new index of every path stored in the named bucket, replacing any existing index.
+// It returns the number of paths in the index.
+func Build(tx *bolt.Tx, bucket string) (int, error) {
+	data := tx.Bucket([]byte(bucket))
+	if data == nil {
+		return 0, bberr.ErrBucketNotFound
+	}
+	if err := Drop(tx, bucket); err != nil {
+		return 0, err
+	}
+	if err := Create(tx, bucket); err != nil {
+		return 0, err
+	}
+	b := lookup(tx, bucket)
+	ib, pb, gb := b.Bucket(ids), b.Bucket(paths), b.Bucket(grams)
+	// the paths and ids are added in order, so their pages can be filled
+	ib.FillPercent, pb.FillPercent = 1, 1
+	// the offsets of each block are kept in memory and saved once the block is full
+	tally, offsets := map[string]uint64{}, map[string][]byte{}
+	flush := func(blk uint64) error {
+		for g, list := range offsets {
+			if err := gb.Put(key([]byte(g), blk), list); err != nil {
+				return err
+			}
+		}
+		clear(offsets)
+		return nil
+	}
+	var seq uint64
+	err := data.ForEach(func(k, _ []byte) error {
+		path := slices.Clone(k)
+		if blk := seq / block; seq > 0 && seq%block == 0 {
+			if err := flush(blk - 1); err != nil {
+				return err
+			}
+		}
+		id := binary.BigEndian.AppendUint64(nil, seq)
+		if err := ib.Put(path, id); err != nil {
+			return err
+		}
+		if err := pb.Put(id, path); err != nil {
+			return err
+		}
+		for _, g := range Grams(path) {
+			tally[string(g)]++
+			offsets[string(g)] = binary.BigEndian.AppendUint16(offsets[string(g)], uint16(seq%block))
+		}
+		seq++
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if seq > 0 {
+		if err := flush((seq - 1) / block); err != nil {
+			return 0, err
+		}
+	}
+	if err := pb.SetSequence(seq); err != nil {
+		return 0, err
+	}
+	cb := b.Bucket(counts)
+	for g, n := range tally {
+		if err := cb.Put([]byte(g), binary.BigEndian.AppendUint64(nil, n)); err != nil {
+			return 0, err
+		}
+	}
+	return int(seq), nil //nolint:gosec
+}
+
+// Drop the index of the named bucket.
+func Drop(tx *bolt.Tx, bucket string) error {
+	root := tx.Bucket([]byte(Name))
+	if root == nil || root.Bucket([]byte(bucket)) == nil {
+		return nil
+	}
+	return root.DeleteBucket([]byte(bucket))
+}
+
+// Rename the index of the named bucket to use the target bucket, which must already hold the items.
+// The named bucket without an index leaves the target without an index.
+func Rename(tx *bolt.Tx, name, target string) error {
+	if !Exists(tx, name) {
+		return Drop(tx, target)
+	}
+	if err := Drop(tx, name); err != nil {
+		return err
+	}
+	_, err := Build(tx, target)
+	return err
+}
+
+// Add the path to the index of the named bucket.
+// Nothing is added when the bucket has no index, as a partial index would miss the other paths.
+func Add(tx *bolt.Tx, bucket string, path []byte) error {
+	b := lookup(tx, bucket)
+	if b == nil {
+		return nil
+	}
+	ib, pb := b.Bucket(ids), b.Bucket(paths)
+	if ib.Get(path) != nil {
+		return nil
+	}
+	path = slices.Clone(path)
+	seq, err := pb.NextSequence()
+	if err != nil {
+		return err
+	}
+	// the first id of a new index is 0
+	seq--
+	id := binary.BigEndian.AppendUint64(nil, seq)
+	if err := ib.Put(path, id); err != nil {
+		return err
+	}
+	if err := pb.Put(id, path); err != nil {
+		return err
+	}
+	gb, cb := b.Bucket(grams), b.Bucket(counts)
+	for _, g := range Grams(path) {
+		k := key(g, seq/block)
+		list, ok := insert(gb.Get(k), uint16(seq%block))
+		if !ok {
+			continue
+		}
+		if err := gb.Put(k, list); err != nil {
+			return err
+		}
+		if err := cb.Put(g, binary.BigEndian.AppendUint64(nil, count(cb, g)+1)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete the path from the index of the named bucket.
+func Delete(tx *bolt.Tx, bucket string, path []byte) error {
+	b := lookup(tx, bucket)
+	if b == nil {
+		return nil
+	}
+	ib, pb := b.Bucket(ids), b.Bucket(paths)
+	id := ib.Get(path)
+	if len(id) != idLen {
+		return nil
+	}
+	seq := binary.BigEndian.Uint64(id)
+	gb, cb := b.Bucket(grams), b.Bucket(counts)
+	for _, g := range Grams(path) {
+		k := key(g, seq/block)
+		list, ok := remove(gb.Get(k), uint16(seq%block))
+		if !ok {
+			continue
+		}
+		var err error
+		if len(list) == 0 {
+			err = gb.Delete(k)
+		} else {
+			err = gb.Put(k, list)
+		}
+		if err != nil {
+			return err
+		}
+		if n := count(cb, g); n > 1 {
+			err = cb.Put(g, binary.BigEndian.AppendUint64(nil, n-1))
+		} else {
+			err = cb.Delete(g)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := pb.Delete(binary.BigEndian.AppendUint64(nil, seq)); err != nil {
+		return err
+	}
+	return ib.Delete(path)
+}
+
+// Candidates returns the paths of the named bucket that contain every trigram of the term, ignoring case.
+// The paths still need to be compared with the term, as the trigrams can be in a different order.
+// It returns false when the term is too short to use the index or the bucket has no index.
+func Candidates(tx *bolt.Tx, bucket string, term []byte) ([][]byte, bool) {
+	gs := Grams(term)
+	if len(gs) == 0 {
+		return nil, false
+	}
+	b := lookup(tx, bucket)
+	if b == nil {
+		return nil, false
+	}
+	gb, cb, pb := b.Bucket(grams), b.Bucket(counts), b.Bucket(paths)
+	// the least used trigram has the fewest blocks to check
+	slices.SortFunc(gs, func(x, y []byte) int {
+		return cmp.Compare(count(cb, x), count(cb, y))
+	})
+	found := [][]byte{}
+	if count(cb, gs[0]) == 0 {
+		return found, true
+	}
+	c := gb.Cursor()
+	for k, v := c.Seek(gs[0]); k != nil && bytes.HasPrefix(k, gs[0]); k, v = c.Next() {
+		blk := binary.BigEndian.Uint64(k[N:])
+		list := v
+		for _, g := range gs[1:] {
+			if list = intersect(list, gb.Get(key(g, blk))); len(list) == 0 {
+				break
+			}
+		}
+		for i := 0; i+postLen <= len(list); i += postLen {
+			seq := blk*block + uint64(binary.BigEndian.Uint16(list[i:]))
+			if path := pb.Get(binary.BigEndian.AppendUint64(nil, seq)); path != nil {
+				found = append(found, slices.Clone(path))
+			}
+		}
+	}
+	return found, true
+}
+
+// lookup returns the index of the named bucket, or nil when it has no index.
+func lookup(tx *bolt.Tx, bucket string) *bolt.Bucket {
+	root := tx.Bucket([]byte(Name))
+	if root == nil {
+		return nil
+	}
+	return root.Bucket([]byte(bucket))
+}
+
+// key returns the key of the trigram offsets within the numbered block.
+func key(g []byte, blk uint64) []byte {
+	return binary.BigEndian.AppendUint64(slices.Clone(g), blk)
+}
+
+// count returns the number of paths that use the trigram.
+func count(cb *bolt.Bucket, g []byte) uint64 {
+	v := cb.Get(g)
+	if len(v) != idLen {
+		return 0
+	}
+	return binary.BigEndian.Uint64(v)
+}
+
+// insert returns a copy of the sorted list with the offset, or false when the list already has the offset.
+func insert(list []byte, off uint16) ([]byte, bool) {
+	i, found := find(list, off)
+	if found {
+		return nil, false
+	}
+	n := make([]byte, 0, len(list)+postLen)
+	n = append(n, list[:i*postLen]...)
+	n = binary.BigEndian.AppendUint16(n, off)
+	return append(n, list[i*postLen:]...), true
+}
+
+// remove returns a copy of the sorted list without the offset, or false when the list does not have the offset.
+func remove(list []byte, off uint16) ([]byte, bool) {
+	i, found := find(list, off)
+	if !found {
+		return nil, false
+	}
+	n := make([]byte, 0, len(list)-postLen)
+	n = append(n, list[:i*postLen]...)
+	return append(n, list[(i+1)*postLen:]...), true
+}
+
+// find returns the position of the offset in the sorted list, and true when the list has the offset.
+func find(list []byte, off uint16) (int, bool) {
+	lo, hi := 0, len(list)/postLen
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1) //nolint:gosec
+		v := binary.BigEndian.Uint16(list[mid*postLen:])
+		switch {
+		case v == off:
+			return mid, true
+		case v < off:
+			lo = mid + 1
+		default:
+			hi = mid
+		}
+	}
+	return lo, false
+}
+
+// intersect returns the offsets that are in both sorted lists.
+func intersect(x, y []byte) []byte {
+	n := []byte{}
+	for i, j := 0, 0; i+postLen <= len(x) && j+postLen <= len(y); {
+		a, b := binary.BigEndian.Uint16(x[i:]), binary.BigEndian.Uint16(y[j:])
+		switch {
+		case a == b:
+			n = binary.BigEndian.AppendUint16(n, a)
+			i += postLen
+			j += postLen
+		case a < b:
+			i += postLen
+		default:
+			j += postLen
+		}
+	}
+	return n
+}
@@ -0,0 +1,162 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+package index_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/bengarrett/dupers/pkg/database/index"
+	"github.com/nalgeon/be"
+	bolt "go.etcd.io/bbolt"
+)
+
+const bucket = "/home/user/photos"
+
+func open(t *testing.T) *bolt.DB {
+	t.Helper()
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "index.db"), 0o600, nil)
+	be.Err(t, err, nil)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func candidates(t *testing.T, db *bolt.DB, term string) ([]string, bool) {
+	t.Helper()
+	var names []string
+	var ok bool
+	err := db.View(func(tx *bolt.Tx) error {
+		var keys [][]byte
+		keys, ok = index.Candidates(tx, bucket, []byte(term))
+		for _, k := range keys {
+			names = append(names, string(k))
+		}
+		return nil
+	})
+	be.Err(t, err, nil)
+	slices.Sort(names)
+	return names, ok
+}
+
+func TestGrams(t *testing.T) {
+	be.Equal(t, len(index.Grams(nil)), 0)
+	be.Equal(t, len(index.Grams([]byte("ab"))), 0)
+	gs := index.Grams([]byte("AbCabc"))
+	be.Equal(t, len(gs), 3)
+	be.Equal(t, string(gs[0]), "abc")
+	be.Equal(t, string(gs[1]), "bca")
+	be.Equal(t, string(gs[2]), "cab")
+}
+
+func TestCandidates(t *testing.T) {
+	db := open(t)
+	a, b := bucket+"/Holiday.jpg", bucket+"/birthday.png"
+	err := db.Update(func(tx *bolt.Tx) error {
+		data, err := tx.CreateBucket([]byte(bucket))
+		be.Err(t, err, nil)
+		be.Err(t, data.Put([]byte(a), nil), nil)
+		// without an index, the path is not added
+		be.Err(t, index.Add(tx, bucket, []byte(a)), nil)
+		be.True(t, !index.Exists(tx, bucket))
+		be.Err(t, index.Create(tx, bucket), nil)
+		be.True(t, index.Exists(tx, bucket))
+		be.Err(t, index.Add(tx, bucket, []byte(a)), nil)
+		be.Err(t, index.Add(tx, bucket, []byte(a)), nil)
+		be.Err(t, data.Put([]byte(b), nil), nil)
+		return index.Add(tx, bucket, []byte(b))
+	})
+	be.Err(t, err, nil)
+
+	names, ok := candidates(t, db, "da")
+	be.True(t, !ok)
+	be.Equal(t, len(names), 0)
+	names, ok = candidates(t, db, "DAY")
+	be.True(t, ok)
+	be.Equal(t, names, []string{a, b})
+	names, ok = candidates(t, db, "holi")
+	be.True(t, ok)
+	be.Equal(t, names, []string{a})
+	names, ok = candidates(t, db, "xyz")
+	be.True(t, ok)
+	be.Equal(t, len(names), 0)
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		return index.Delete(tx, bucket, []byte(a))
+	})
+	be.Err(t, err, nil)
+	names, _ = candidates(t, db, "day")
+	be.Equal(t, names, []string{b})
+	names, _ = candidates(t, db, "holi")
+	be.Equal(t, len(names), 0)
+}
+
+func TestBuild(t *testing.T) {
+	db := open(t)
+	a, b := bucket+"/a.jpg", bucket+"/b.jpg"
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := index.Build(tx, bucket)
+		be.True(t, err != nil)
+		data, err := tx.CreateBucket([]byte(bucket))
+		be.Err(t, err, nil)
+		be.Err(t, data.Put([]byte(a), nil), nil)
+		be.Err(t, data.Put([]byte(b), nil), nil)
+		n, err := index.Build(tx, bucket)
+		be.Equal(t, n, 2)
+		return err
+	})
+	be.Err(t, err, nil)
+	names, ok := candidates(t, db, ".jpg")
+	be.True(t, ok)
+	be.Equal(t, names, []string{a, b})
+
+	const target = "/mnt/photos"
+	err = db.Update(func(tx *bolt.Tx) error {
+		data, err := tx.CreateBucket([]byte(target))
+		be.Err(t, err, nil)
+		be.Err(t, data.Put([]byte(a), nil), nil)
+		be.Err(t, index.Rename(tx, bucket, target), nil)
+		be.True(t, !index.Exists(tx, bucket))
+		be.True(t, index.Exists(tx, target))
+		be.Err(t, index.Drop(tx, target), nil)
+		be.True(t, !index.Exists(tx, target))
+		return index.Drop(tx, target)
+	})
+	be.Err(t, err, nil)
+}
+
+func TestBuild_Blocks(t *testing.T) {
+	db := open(t)
+	const items = 5000
+	name := func(i int) string {
+		return fmt.Sprintf("%s/%04d.jpg", bucket, i)
+	}
+	err := db.Update(func(tx *bolt.Tx) error {
+		data, err := tx.CreateBucket([]byte(bucket))
+		be.Err(t, err, nil)
+		for i := range items {
+			be.Err(t, data.Put([]byte(name(i)), nil), nil)
+		}
+		n, err := index.Build(tx, bucket)
+		be.Equal(t, n, items)
+		return err
+	})
+	be.Err(t, err, nil)
+	names, ok := candidates(t, db, ".jpg")
+	be.True(t, ok)
+	be.Equal(t, len(names), items)
+	names, _ = candidates(t, db, "/4999.")
+	be.Equal(t, names, []string{name(4999)})
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		be.Err(t, index.Delete(tx, bucket, []byte(name(4999))), nil)
+		return index.Add(tx, bucket, []byte(bucket+"/new.jpg"))
+	})
+	be.Err(t, err, nil)
+	names, _ = candidates(t, db, "/4999.")
+	be.Equal(t, len(names), 0)
+	names, _ = candidates(t, db, "new.jpg")
+	be.Equal(t, names, []string{bucket + "/new.jpg"})
+	names, _ = candidates(t, db, ".jpg")
+	be.Equal(t, len(names), items)
+}
@@ -14,6 +14,8 @@ import (
 
 	"github.com/bengarrett/dupers/internal/printer"
 	"github.com/bengarrett/dupers/pkg/database/csv"
+	"github.com/bengarrett/dupers/pkg/database/index"
+	"github.com/bengarrett/dupers/pkg/database/record"
 	"github.com/gookit/color"
 	bolt "go.etcd.io/bbolt"
 	bberr "go.etcd.io/bbolt/errors"
@@ -46,7 +48,7 @@ func Backup() (string, int64, error) {
 	if err != nil {
 		return "", 0, err
 	}
-	dest := filepath.Join(dir, backup())
+	dest := filepath.Join(dir, backup(src))
 	written, err := CopyFile(src, dest)
 	if err != nil {
 		return "", 0, err
@@ -54,10 +56,11 @@ func Backup() (string, int64, error) {
 	return dest, written, nil
 }
 
-// backup generates a time sensitive name for the backup file.
-func backup() string {
-	now, ext := time.Now().Format(backupTime), filepath.Ext(boltName)
-	return fmt.Sprintf("%s-backup-%s%s", strings.TrimSuffix(boltName, ext), now, ext)
+// backup generates a time sensitive name for the backup file of the named database.
+func backup(db string) string {
+	name := filepath.Base(db)
+	now, ext := time.Now().Format(backupTime), filepath.Ext(name)
+	return fmt.Sprintf("%s-backup-%s%s", strings.TrimSuffix(name, ext), now, ext)
 }
 
 // CopyFile duplicates the named file to the destination filepath.
@@ -95,7 +98,7 @@ func CSVExport(db *bolt.DB, bucket string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	name := filepath.Join(dir, export())
+	name := filepath.Join(dir, export(db.Path()))
 	name = filepath.Clean(name)
 	dest, err := os.Create(name)
 	if err != nil {
@@ -122,10 +125,15 @@ func CSVExport(db *bolt.DB, bucket string) (string, error) {
 	return name, nil
 }
 
-// export generates a time sensitive name for the export file.
-func export() string {
+// export generates a time sensitive name for the export file of the named database.
+// A database other than dupers.db prefixes the export file with its name.
+func export(db string) string {
 	now, ext := time.Now().Format(backupTime), filepath.Ext(csvName)
-	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(csvName, ext), now, ext)
+	name := strings.TrimSuffix(csvName, ext)
+	if base := filepath.Base(db); base != boltName && base != "." && base != "" {
+		name = strings.TrimSuffix(base, filepath.Ext(base)) + "-" + strings.TrimPrefix(name, "dupers-")
+	}
+	return fmt.Sprintf("%s-%s%s", name, now, ext)
 }
 
 // CSVImport reads the named csv export file and imports its content to the database.
@@ -220,12 +228,26 @@ func (batch Lists) iterate(db *bolt.DB, name Bucket, imported, total int) (int,
 	}
 	for path, sum := range batch {
 		if err := db.Update(func(tx *bolt.Tx) error {
-			b, err := tx.CreateBucketIfNotExists([]byte(name))
-			if err != nil {
-				return err
+			b := tx.Bucket([]byte(name))
+			if b == nil {
+				var err error
+				if b, err = tx.CreateBucket([]byte(name)); err != nil {
+					return err
+				}
+				if err := index.Create(tx, string(name)); err != nil {
+					return err
+				}
 			}
 			_, _ = fmt.Fprint(os.Stdout, printer.Status(imported, total, printer.Read))
-			if err := b.Put([]byte(string(path)), sum[:]); err != nil {
+			// keep any existing file stat data for an unchanged checksum
+			if r, err := record.Decode(b.Get([]byte(path))); err == nil && r.Sum == sum {
+				imported++
+				return nil
+			}
+			if err := b.Put([]byte(path), record.New(sum, nil).Bytes()); err != nil {
+				return err
+			}
+			if err := index.Add(tx, string(name), []byte(path)); err != nil {
 				return err
 			}
 			imported++
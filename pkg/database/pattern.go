@@ -0,0 +1,127 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+package database
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/bengarrett/dupers/internal/glob"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	ErrGlob   = errors.New("search term is not a valid glob pattern")
+	ErrRegexp = errors.New("search term is not a valid regular expression")
+)
+
+// Pattern is a search term compiled from a regular expression or a glob.
+type Pattern struct {
+	re    *regexp.Regexp
+	base  bool // base matches the filename, instead of the full path.
+	slash bool // slash matches the path using forward slashes, which are used by the globs.
+}
+
+// Regexp compiles the regular expression search term.
+// The term is case insensitive unless exact is true, and it matches only the filename when base is true.
+func Regexp(term string, exact, base bool) (*Pattern, error) {
+	if term == "" {
+		return nil, ErrNoTerm
+	}
+	re, err := regexp.Compile(term)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRegexp, err)
+	}
+	if !exact {
+		re = regexp.MustCompile("(?i)" + term)
+	}
+	return &Pattern{re: re, base: base}, nil
+}
+
+// Glob compiles the glob search term, using the *, **, ? and [] syntax of the .dupersignore files.
+// The glob must match the whole filename, or when it contains a slash, the end of the path.
+// A glob that begins with a slash must match the full path.
+// The term is case insensitive unless exact is true, and it matches only the filename when base is true.
+func Glob(term string, exact, base bool) (*Pattern, error) {
+	if term == "" {
+		return nil, ErrNoTerm
+	}
+	pattern := filepath.ToSlash(term)
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("%w: %q: %w", ErrGlob, term, err)
+	}
+	expr := "^" + glob.Expr(pattern) + "$"
+	switch {
+	case base || !strings.Contains(pattern, "/"):
+		base = true
+	case !strings.HasPrefix(pattern, "/"):
+		// match the glob from any directory in the path
+		expr = "(?:^|/)" + glob.Expr(pattern) + "$"
+	}
+	if !exact {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q: %w", ErrGlob, term, err)
+	}
+	return &Pattern{re: re, base: base, slash: true}, nil
+}
+
+// Match returns true when the pattern matches the named path.
+func (p *Pattern) Match(name string) bool {
+	if p == nil {
+		return false
+	}
+	s, _ := p.target(name)
+	return p.re.MatchString(s)
+}
+
+// Index returns the start and end positions of every match in the named path, for use as highlights.
+func (p *Pattern) Index(name string) [][]int {
+	if p == nil {
+		return nil
+	}
+	s, offset := p.target(name)
+	spans := p.re.FindAllStringIndex(s, -1)
+	for _, span := range spans {
+		span[0] += offset
+		span[1] += offset
+	}
+	return spans
+}
+
+// String returns the regular expression of the pattern.
+func (p *Pattern) String() string {
+	if p == nil {
+		return ""
+	}
+	return p.re.String()
+}
+
+// target returns the part of the named path to match and its offset within the path.
+// Both the base name and the forward slashes keep the byte positions of the path.
+func (p *Pattern) target(name string) (string, int) {
+	s, offset := name, 0
+	if p.base {
+		s = filepath.Base(name)
+		offset = strings.LastIndex(name, s)
+	}
+	if p.slash {
+		s = filepath.ToSlash(s)
+	}
+	return s, offset
+}
+
+// ComparePattern finds the stored filenames and paths that match the regular expression or glob pattern.
+func ComparePattern(db *bolt.DB, p *Pattern, buckets ...string) (*Matches, error) {
+	if p == nil {
+		return nil, ErrNoTerm
+	}
+	return search(db, nil, func(key, _ []byte) bool {
+		return p.Match(string(key))
+	}, buckets...)
+}
@@ -0,0 +1,83 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+package database_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/bengarrett/dupers/internal/mock"
+	"github.com/bengarrett/dupers/pkg/database"
+	"github.com/nalgeon/be"
+)
+
+func TestRegexp(t *testing.T) {
+	p, err := database.Regexp("", false, false)
+	be.Err(t, err, database.ErrNoTerm)
+	be.Equal(t, p, nil)
+	_, err = database.Regexp("(", false, false)
+	be.Err(t, err, database.ErrRegexp)
+
+	p, err = database.Regexp(`photo\d+\.JPG$`, false, false)
+	be.Err(t, err, nil)
+	be.True(t, p.Match("/home/user/photo01.jpg"))
+	be.True(t, !p.Match("/home/user/photo.jpg"))
+	p, err = database.Regexp(`photo\d+\.JPG$`, true, false)
+	be.Err(t, err, nil)
+	be.True(t, !p.Match("/home/user/photo01.jpg"))
+
+	p, err = database.Regexp("^user", false, true)
+	be.Err(t, err, nil)
+	be.True(t, !p.Match("/home/user/photo01.jpg"))
+	be.True(t, p.Match("/home/user/user.txt"))
+	be.Equal(t, p.Index("/home/user/user.txt"), [][]int{{11, 15}})
+}
+
+func TestGlob(t *testing.T) {
+	_, err := database.Glob("", false, false)
+	be.Err(t, err, database.ErrNoTerm)
+	_, err = database.Glob("[", false, false)
+	be.Err(t, err, database.ErrGlob)
+
+	p, err := database.Glob("*.JPG", false, false)
+	be.Err(t, err, nil)
+	be.True(t, p.Match("/home/user/photo01.jpg"))
+	be.True(t, !p.Match("/home/user/photo01.jpg.txt"))
+	be.Equal(t, p.Index("/home/user/photo01.jpg"), [][]int{{11, 22}})
+
+	p, err = database.Glob("photos/**/*.png", false, false)
+	be.Err(t, err, nil)
+	be.True(t, p.Match("/home/user/photos/a.png"))
+	be.True(t, p.Match("/home/user/photos/2024/june/a.png"))
+	be.True(t, !p.Match("/home/user/myphotos/a.png"))
+
+	p, err = database.Glob("/home/*/a.png", false, false)
+	be.Err(t, err, nil)
+	be.True(t, p.Match("/home/user/a.png"))
+	be.True(t, !p.Match("/mnt/home/user/a.png"))
+
+	p, err = database.Glob("photos/*.png", false, true)
+	be.Err(t, err, nil)
+	be.True(t, !p.Match("/home/user/photos/a.png"))
+}
+
+func TestComparePattern(t *testing.T) {
+	db, path := mock.Database(t)
+	defer db.Close()
+	defer os.Remove(path)
+	_, err := database.ComparePattern(db, nil)
+	be.Err(t, err, database.ErrNoTerm)
+
+	p, err := database.Glob("[1-9]*", true, false)
+	be.Err(t, err, nil)
+	m, err := database.ComparePattern(db, p)
+	be.Err(t, err, nil)
+	be.Equal(t, len(*m), 2)
+	_, ok := (*m)[database.Filepath(mock.Item(t, 1))]
+	be.True(t, ok)
+
+	p, err = database.Regexp("^zzz", false, true)
+	be.Err(t, err, nil)
+	m, err = database.ComparePattern(db, p)
+	be.Err(t, err, nil)
+	be.Equal(t, len(*m), 0)
+}
@@ -0,0 +1,119 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+
+// Package record provides the versioned values that are stored for each item in a database bucket.
+package record
+
+import (
+	"encoding/binary"
+	"errors"
+	"io/fs"
+	"time"
+)
+
+// Value layouts stored in the database buckets.
+//
+// The legacy layout is a bare, 32 byte SHA-256 checksum.
+// The version 1 layout is a version byte followed by the checksum,
+// and the size, modification time, inode and device of the file.
+const (
+	V1 byte = 1 // V1 is the version byte for values that contain file stat data.
+
+	LegacyLen = sumLen                       // LegacyLen is the byte length of a legacy, checksum only value.
+	V1Len     = 1 + sumLen + fieldLen*fields // V1Len is the byte length of a version 1 value.
+
+	sumLen   = 32
+	fieldLen = 8
+	fields   = 4
+)
+
+var ErrValue = errors.New("bucket item value is not a known record layout")
+
+// Record is the checksum and file stat data stored as the value of a bucket item.
+type Record struct {
+	Sum     [32]byte  // Sum is the SHA-256 checksum of the file content.
+	Size    int64     // Size of the file in bytes.
+	ModTime time.Time // ModTime is the modification time of the file.
+	Inode   uint64    // Inode number of the file, this is always 0 on Windows.
+	Dev     uint64    // Dev is the device that contains the file, this is always 0 on Windows.
+}
+
+// New returns a record of the checksum and the file stat data.
+// A nil info returns a record that only contains the checksum.
+func New(sum [32]byte, info fs.FileInfo) Record {
+	r := Record{Sum: sum}
+	if info == nil {
+		return r
+	}
+	r.Size = info.Size()
+	r.ModTime = info.ModTime()
+	r.Inode, r.Dev = sys(info)
+	return r
+}
+
+// Decode the bucket item value using either the legacy or the version 1 layout.
+func Decode(b []byte) (Record, error) {
+	var r Record
+	switch {
+	case len(b) == LegacyLen:
+		copy(r.Sum[:], b)
+		return r, nil
+	case len(b) == V1Len && b[0] == V1:
+		b = b[1:]
+		copy(r.Sum[:], b[:sumLen])
+		b = b[sumLen:]
+		r.Size = int64(binary.BigEndian.Uint64(b[0:fieldLen]))                       //nolint:gosec
+		if ns := int64(binary.BigEndian.Uint64(b[fieldLen : fieldLen*2])); ns != 0 { //nolint:gosec
+			r.ModTime = time.Unix(0, ns)
+		}
+		r.Inode = binary.BigEndian.Uint64(b[fieldLen*2 : fieldLen*3])
+		r.Dev = binary.BigEndian.Uint64(b[fieldLen*3 : fieldLen*4])
+		return r, nil
+	}
+	return r, ErrValue
+}
+
+// Bytes encodes the record as a bucket item value.
+// A record without any file stat data uses the smaller, legacy layout.
+func (r Record) Bytes() []byte {
+	if !r.Stat() {
+		b := make([]byte, LegacyLen)
+		copy(b, r.Sum[:])
+		return b
+	}
+	b := make([]byte, 0, V1Len)
+	b = append(b, V1)
+	b = append(b, r.Sum[:]...)
+	b = binary.BigEndian.AppendUint64(b, uint64(r.Size)) //nolint:gosec
+	ns := int64(0)
+	if !r.ModTime.IsZero() {
+		ns = r.ModTime.UnixNano()
+	}
+	b = binary.BigEndian.AppendUint64(b, uint64(ns)) //nolint:gosec
+	b = binary.BigEndian.AppendUint64(b, r.Inode)
+	b = binary.BigEndian.AppendUint64(b, r.Dev)
+	return b
+}
+
+// Same returns true when the record stat data matches the size and modification time of the file.
+// A record without file stat data is never the same.
+func (r Record) Same(info fs.FileInfo) bool {
+	if info == nil || !r.Stat() {
+		return false
+	}
+	return r.Size == info.Size() && r.ModTime.Equal(info.ModTime())
+}
+
+// ID returns the inode and device numbers as a key to identify a file that has been moved or renamed.
+// The returned bool is false when the record has no inode.
+func (r Record) ID() ([2]uint64, bool) {
+	if r.Inode == 0 {
+		return [2]uint64{}, false
+	}
+	return [2]uint64{r.Inode, r.Dev}, true
+}
+
+// Stat returns true when the record contains file stat data.
+// Records decoded from the legacy layout only contain a checksum.
+func (r Record) Stat() bool {
+	return r.Size != 0 || !r.ModTime.IsZero() || r.Inode != 0 || r.Dev != 0
+}
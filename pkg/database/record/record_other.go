@@ -0,0 +1,12 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+
+//go:build !unix
+
+package record
+
+import "io/fs"
+
+// sys returns zero values as inode and device numbers are not used by this platform.
+func sys(_ fs.FileInfo) (uint64, uint64) {
+	return 0, 0
+}
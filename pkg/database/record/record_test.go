@@ -0,0 +1,46 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+package record_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/bengarrett/dupers/internal/mock"
+	"github.com/bengarrett/dupers/pkg/database/record"
+	"github.com/nalgeon/be"
+)
+
+func TestDecode(t *testing.T) {
+	_, err := record.Decode(nil)
+	be.Err(t, err, record.ErrValue)
+	_, err = record.Decode([]byte("test-hash"))
+	be.Err(t, err, record.ErrValue)
+	var sum [32]byte
+	copy(sum[:], "a pretend sha-256 checksum value")
+	r, err := record.Decode(sum[:])
+	be.Err(t, err, nil)
+	be.Equal(t, r.Sum, sum)
+	be.Equal(t, r.Stat(), false)
+}
+
+func TestRecord_Bytes(t *testing.T) {
+	var sum [32]byte
+	copy(sum[:], "a pretend sha-256 checksum value")
+	b := record.New(sum, nil).Bytes()
+	be.Equal(t, len(b), record.LegacyLen)
+	item := mock.Item(t, 1)
+	info, err := os.Stat(item)
+	be.Err(t, err, nil)
+	r := record.New(sum, info)
+	be.True(t, r.Stat())
+	b = r.Bytes()
+	be.Equal(t, len(b), record.V1Len)
+	be.Equal(t, b[0], record.V1)
+	d, err := record.Decode(b)
+	be.Err(t, err, nil)
+	be.Equal(t, d.Sum, sum)
+	be.Equal(t, d.Size, info.Size())
+	be.True(t, d.ModTime.Equal(info.ModTime()))
+	be.Equal(t, d.Inode, r.Inode)
+	be.Equal(t, d.Dev, r.Dev)
+}
@@ -0,0 +1,19 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+
+//go:build unix
+
+package record
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// sys returns the inode and device numbers of the file.
+func sys(info fs.FileInfo) (uint64, uint64) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || st == nil {
+		return 0, 0
+	}
+	return uint64(st.Ino), uint64(st.Dev) //nolint:unconvert,gosec
+}
@@ -0,0 +1,208 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+package database
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dustin/go-humanize"
+	"github.com/gookit/color"
+	bolt "go.etcd.io/bbolt"
+	bberr "go.etcd.io/bbolt/errors"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// Item is a stored file path and the bucket it was sourced from.
+type Item struct {
+	Path   Filepath
+	Bucket Bucket
+}
+
+// Group is a collection of stored items that share a SHA256 checksum.
+// The first item is the copy that is kept, while every other item is a reclaimable duplicate.
+type Group struct {
+	Sum   [32]byte // Sum is the SHA256 checksum shared by the items.
+	Size  int64    // Size of each item in bytes.
+	Items []Item   // Items are sorted by bucket and path.
+}
+
+// Wasted returns the number of bytes used by the duplicates in the group.
+func (g Group) Wasted() int64 {
+	if len(g.Items) < 2 {
+		return 0
+	}
+	return g.Size * int64(len(g.Items)-1)
+}
+
+// Report returns the duplicate groups stored in the named buckets, sorted by the most wasted bytes.
+// All the buckets are used when none are named.
+//
+// The size of items saved by older versions of dupers is read from the file system.
+// Empty files and items with an unknown size are ignored, as they waste no known space.
+// Hard links to the same file are only counted once, as they share the same data.
+func Report(db *bolt.DB, buckets ...string) ([]Group, error) {
+	if db == nil {
+		return nil, bberr.ErrDatabaseNotOpen
+	}
+	if len(buckets) == 0 {
+		var err error
+		if buckets, err = All(db); err != nil {
+			return nil, err
+		}
+	}
+	groups := make(map[[32]byte]*Group)
+	ids := make(map[Filepath][2]uint64)
+	for _, name := range buckets {
+		records, err := ListRecords(db, name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, name)
+		}
+		for path, r := range records {
+			size := r.Size
+			if !r.Stat() {
+				stat, err := os.Stat(string(path))
+				if err != nil {
+					continue
+				}
+				size = stat.Size()
+			}
+			if size == 0 {
+				continue
+			}
+			if id, ok := r.ID(); ok {
+				ids[path] = id
+			}
+			g, ok := groups[r.Sum]
+			if !ok {
+				g = &Group{Sum: r.Sum, Size: size}
+				groups[r.Sum] = g
+			}
+			g.Items = append(g.Items, Item{Path: path, Bucket: Bucket(name)})
+		}
+	}
+	s := make([]Group, 0, len(groups))
+	for _, g := range groups {
+		slices.SortFunc(g.Items, func(a, b Item) int {
+			if n := strings.Compare(string(a.Bucket), string(b.Bucket)); n != 0 {
+				return n
+			}
+			return strings.Compare(string(a.Path), string(b.Path))
+		})
+		g.Items = unlinked(g.Items, ids)
+		if len(g.Items) < 2 {
+			continue
+		}
+		s = append(s, *g)
+	}
+	slices.SortFunc(s, func(a, b Group) int {
+		if a.Wasted() != b.Wasted() {
+			if a.Wasted() > b.Wasted() {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(string(a.Items[0].Path), string(b.Items[0].Path))
+	})
+	return s, nil
+}
+
+// unlinked removes the items that are hard links to the file of an earlier item.
+// The ids are the inode and device numbers of the items, items without an id are always kept.
+func unlinked(items []Item, ids map[Filepath][2]uint64) []Item {
+	seen := make(map[[2]uint64]bool, len(items))
+	return slices.DeleteFunc(items, func(item Item) bool {
+		id, ok := ids[item.Path]
+		if !ok {
+			return false
+		}
+		if seen[id] {
+			return true
+		}
+		seen[id] = true
+		return false
+	})
+}
+
+// ReportInfo returns a printout of the duplicate groups together with the
+// number of duplicate files and the reclaimable space of each group and bucket.
+func ReportInfo(groups ...Group) (string, error) {
+	var b bytes.Buffer
+	if len(groups) == 0 {
+		printl(&b, "No duplicate files are stored in the buckets.")
+		return b.String(), nil
+	}
+	type vals struct {
+		files  int
+		wasted int64
+	}
+	buckets, names := map[Bucket]vals{}, []Bucket{}
+	files, wasted := 0, int64(0)
+	p := message.NewPrinter(language.English)
+	tab := tabwriter.NewWriter(&b, 0, 0, tabPadding, ' ', tabwriter.AlignRight)
+	printf(tab, "Files\tReclaimable\t\tChecksum %s", color.Secondary.Sprint("(duplicate paths)"))
+	printl(tab)
+	for _, g := range groups {
+		_, _ = p.Fprintf(tab, "%d\t%s\t\t%s", number.Decimal(len(g.Items)),
+			humanize.Bytes(safesize(g.Wasted())), hex.EncodeToString(g.Sum[:]))
+		printl(tab)
+		for i, item := range g.Items {
+			if i == 0 {
+				printf(tab, "\t\t\t  %s", item.Path)
+				printl(tab)
+				continue
+			}
+			printf(tab, "\t\t\t%s%s", color.Success.Sprint("⤷ "), item.Path)
+			printl(tab)
+			v, ok := buckets[item.Bucket]
+			if !ok {
+				names = append(names, item.Bucket)
+			}
+			v.files++
+			v.wasted += g.Size
+			buckets[item.Bucket] = v
+		}
+		files += len(g.Items) - 1
+		wasted += g.Wasted()
+	}
+	if err := tab.Flush(); err != nil {
+		return b.String(), err
+	}
+	printl(&b)
+	slices.SortFunc(names, func(x, y Bucket) int {
+		if buckets[x].wasted != buckets[y].wasted {
+			if buckets[x].wasted > buckets[y].wasted {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(string(x), string(y))
+	})
+	tab = tabwriter.NewWriter(&b, 0, 0, tabPadding, ' ', tabwriter.AlignRight)
+	printf(tab, "Duplicates\tReclaimable\t\tBucket %s", color.Secondary.Sprint("(absolute path)"))
+	printl(tab)
+	for _, name := range names {
+		v := buckets[name]
+		_, _ = p.Fprintf(tab, "%d\t%s\t\t%s", number.Decimal(v.files),
+			humanize.Bytes(safesize(v.wasted)), name)
+		printl(tab)
+	}
+	if err := tab.Flush(); err != nil {
+		return b.String(), err
+	}
+	printl(&b)
+	printf(&b, "%s %s in %s groups, %s %s",
+		color.Secondary.Sprint("Total:"),
+		color.Primary.Sprint(p.Sprintf("%d duplicate files", number.Decimal(files))),
+		p.Sprint(number.Decimal(len(groups))),
+		color.Primary.Sprint(humanize.Bytes(safesize(wasted))),
+		color.Secondary.Sprint("is reclaimable"))
+	printl(&b)
+	return b.String(), nil
+}
@@ -0,0 +1,109 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+package database_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bengarrett/dupers/internal/mock"
+	"github.com/bengarrett/dupers/pkg/database"
+	"github.com/bengarrett/dupers/pkg/database/record"
+	"github.com/gookit/color"
+	"github.com/nalgeon/be"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestReport(t *testing.T) {
+	color.Enable = false
+	_, err := database.Report(nil)
+	be.Err(t, err)
+	db, _ := mock.Open(t, filepath.Join(t.TempDir(), "report.db"))
+	defer db.Close()
+	groups, err := database.Report(db)
+	be.Err(t, err, nil)
+	be.Equal(t, len(groups), 0)
+	s, err := database.ReportInfo(groups...)
+	be.Err(t, err, nil)
+	be.True(t, strings.Contains(s, "No duplicate files"))
+
+	const bucketA, bucketB = "/report/a", "/report/b"
+	small, large, unique := [32]byte{1}, [32]byte{2}, [32]byte{3}
+	put := func(bucket, path string, sum [32]byte, size int64) {
+		t.Helper()
+		err := db.Update(func(tx *bolt.Tx) error {
+			b, err := tx.CreateBucketIfNotExists([]byte(bucket))
+			if err != nil {
+				return err
+			}
+			r := record.Record{Sum: sum, Size: size, ModTime: time.Now()}
+			return b.Put([]byte(path), r.Bytes())
+		})
+		be.Err(t, err, nil)
+	}
+	put(bucketA, "/report/a/small1", small, 10)
+	put(bucketA, "/report/a/small2", small, 10)
+	put(bucketA, "/report/a/large", large, 1000)
+	put(bucketB, "/report/b/large1", large, 1000)
+	put(bucketB, "/report/b/large2", large, 1000)
+	put(bucketB, "/report/b/unique", unique, 5)
+	put(bucketB, "/report/b/empty1", [32]byte{4}, 0)
+	put(bucketB, "/report/b/empty2", [32]byte{4}, 0)
+	groups, err = database.Report(db)
+	be.Err(t, err, nil)
+	be.Equal(t, len(groups), 2)
+	be.Equal(t, groups[0].Sum, large)
+	be.Equal(t, groups[0].Wasted(), int64(2000))
+	be.Equal(t, groups[0].Items[0].Path, database.Filepath("/report/a/large"))
+	be.Equal(t, groups[1].Wasted(), int64(10))
+	// named buckets
+	groups, err = database.Report(db, bucketB)
+	be.Err(t, err, nil)
+	be.Equal(t, len(groups), 1)
+	be.Equal(t, groups[0].Wasted(), int64(1000))
+	_, err = database.Report(db, "/report/c")
+	be.Err(t, err)
+	// printout
+	groups, err = database.Report(db)
+	be.Err(t, err, nil)
+	s, err = database.ReportInfo(groups...)
+	be.Err(t, err, nil)
+	be.True(t, strings.Contains(s, "3 duplicate files in 2 groups"))
+	be.True(t, strings.Contains(s, "2.0 kB is reclaimable"))
+	be.True(t, strings.Contains(s, bucketB))
+}
+
+func TestReport_HardLinks(t *testing.T) {
+	db, _ := mock.Open(t, filepath.Join(t.TempDir(), "links.db"))
+	defer db.Close()
+	const bucket = "/report/links"
+	sum := [32]byte{1}
+	put := func(path string, inode uint64) {
+		t.Helper()
+		err := db.Update(func(tx *bolt.Tx) error {
+			b, err := tx.CreateBucketIfNotExists([]byte(bucket))
+			if err != nil {
+				return err
+			}
+			r := record.Record{Sum: sum, Size: 100, ModTime: time.Now(), Inode: inode, Dev: 1}
+			return b.Put([]byte(path), r.Bytes())
+		})
+		be.Err(t, err, nil)
+	}
+	// hard links to the same file waste no space
+	put("/report/links/a", 7)
+	put("/report/links/b", 7)
+	groups, err := database.Report(db)
+	be.Err(t, err, nil)
+	be.Equal(t, len(groups), 0)
+	// a copy with its own inode is reclaimable, but the links are counted once
+	put("/report/links/c", 8)
+	groups, err = database.Report(db)
+	be.Err(t, err, nil)
+	be.Equal(t, len(groups), 1)
+	be.Equal(t, len(groups[0].Items), 2)
+	be.Equal(t, groups[0].Items[0].Path, database.Filepath("/report/links/a"))
+	be.Equal(t, groups[0].Items[1].Path, database.Filepath("/report/links/c"))
+	be.Equal(t, groups[0].Wasted(), int64(100))
+}
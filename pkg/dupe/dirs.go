@@ -0,0 +1,105 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+package dupe
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"os"
+
+	"github.com/bengarrett/dupers/pkg/database"
+	"github.com/bengarrett/dupers/pkg/dupe/parse"
+	"github.com/bengarrett/dupers/pkg/dupe/tree"
+	"github.com/dustin/go-humanize"
+	"github.com/gookit/color"
+	bolt "go.etcd.io/bbolt"
+	bberr "go.etcd.io/bbolt/errors"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// Dirs finds the identical and subset directories within the buckets and the source directory.
+// The buckets use the checksums stored in the database, while the source files are hashed
+// when they could be a duplicate of an item to compare.
+func (c *Config) Dirs(db *bolt.DB) ([]tree.Dirs, []tree.Subset, error) {
+	if db == nil {
+		return nil, nil, bberr.ErrDatabaseNotOpen
+	}
+	c.Debugger("find identical and subset directories.")
+	t := tree.New()
+	for _, bucket := range c.Buckets {
+		name := string(bucket)
+		if name == "" {
+			continue
+		}
+		records, err := database.ListRecords(db, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		for path, r := range records {
+			if c.Filter().Skip(name, string(path)) {
+				continue
+			}
+			t.Add(name, string(path), r.Sum, r.Size)
+		}
+	}
+	if err := c.dirSource(t); err != nil {
+		return nil, nil, err
+	}
+	return t.Identical(), t.Subsets(), nil
+}
+
+// dirSource adds the files of the source directory to the tree.
+// Files that cannot be a duplicate are given a unique checksum instead of being hashed.
+func (c *Config) dirSource(t *tree.Tree) error {
+	src := c.GetSource()
+	if info, err := os.Stat(src); err != nil || !info.IsDir() {
+		return nil //nolint:nilerr
+	}
+	files, err := c.sourceFiles()
+	if err != nil {
+		return err
+	}
+	matches := c.prune(files...)
+	sums, err := c.sums(matches...)
+	if err != nil {
+		return err
+	}
+	known := make(map[string]parse.Checksum, len(matches))
+	for i, name := range matches {
+		known[name] = sums[i]
+	}
+	for _, name := range files {
+		info, err := os.Stat(name)
+		if err != nil {
+			return err
+		}
+		sum, ok := known[name]
+		if !ok {
+			sum = sha256.Sum256([]byte("unmatched\x00" + name))
+		}
+		t.Add(src, name, sum, info.Size())
+	}
+	return nil
+}
+
+// PrintDirs prints the groups of identical directories and the subset directories found by Dirs.
+func PrintDirs(groups []tree.Dirs, subsets []tree.Subset) string {
+	w := new(bytes.Buffer)
+	p := message.NewPrinter(language.English)
+	for _, g := range groups {
+		printf(w, "%s %s\n", color.Info.Sprint("identical directories,"),
+			p.Sprintf("%d files, %s each", g.Files, humanize.Bytes(safesize(g.Size))))
+		for _, path := range g.Paths {
+			printf(w, "  %s\n", path)
+		}
+	}
+	for _, s := range subsets {
+		printf(w, "%s %s\n", color.Info.Sprint("subset directory,"),
+			p.Sprintf("%d files, %s", s.Files, humanize.Bytes(safesize(s.Size))))
+		printf(w, "  %s\n  %s %s\n", s.Path, color.Success.Sprint("⊂"), s.Of)
+	}
+	if len(groups) == 0 && len(subsets) == 0 {
+		printl(w, color.Info.Sprint("\rNo duplicate directories found.          "))
+	}
+	return w.String()
+}
@@ -20,8 +20,13 @@ import (
 
 	"github.com/bengarrett/dupers/internal/printer"
 	"github.com/bengarrett/dupers/pkg/database"
+	"github.com/bengarrett/dupers/pkg/database/index"
+	"github.com/bengarrett/dupers/pkg/database/record"
+	ign "github.com/bengarrett/dupers/pkg/dupe/ignore"
 	"github.com/bengarrett/dupers/pkg/dupe/internal/archive"
 	"github.com/bengarrett/dupers/pkg/dupe/parse"
+	"github.com/bengarrett/dupers/pkg/dupe/quarantine"
+	"github.com/bengarrett/dupers/pkg/dupe/trash"
 	"github.com/bodgit/sevenzip"
 	"github.com/dustin/go-humanize"
 	"github.com/gookit/color"
@@ -71,10 +76,33 @@ func printf(w io.Writer, format string, a ...any) {
 type Config struct {
 	parse.Scanner
 
-	Debug bool // Debug spams technobabble to stdout.
-	Quiet bool // Quiet the feedback sent to stdout.
-	Yes   bool // Yes is assumed for all user questions and prompts.
-	Test  bool // Test toggles the internal unit test mode.
+	Debug   bool // Debug spams technobabble to stdout.
+	Quiet   bool // Quiet the feedback sent to stdout.
+	Yes     bool // Yes is assumed for all user questions and prompts.
+	Test    bool // Test toggles the internal unit test mode.
+	Workers int  // Workers is the number of files hashed at the same time, zero uses every CPU.
+
+	Keep       string // Keep is the policy that chooses which duplicate survives a removal, empty keeps every match.
+	Quarantine string // Quarantine is the directory that removed files are moved into, instead of being deleted.
+	Trash      bool   // Trash moves removed files to the desktop trash, instead of deleting them.
+	DryRun     bool   // DryRun reports what would be removed or replaced, without changing anything.
+	Prune      bool   // Prune also cleans the stored items skipped by the .dupersignore files of the buckets.
+	Journal    string // Journal is the path of the append-only record of removed files, empty disables the record.
+
+	MinSize int64    // MinSize skips the files smaller than this many bytes.
+	MaxSize int64    // MaxSize skips the files larger than this many bytes, zero has no maximum.
+	Empty   bool     // Empty uses the zero byte files, which are otherwise skipped.
+	Include []string // Include globs, when used only the matching files are scanned.
+	Exclude []string // Exclude globs of the files and directories to skip.
+
+	Options map[string]Options // Options are the settings of the buckets, keyed by the absolute bucket path.
+
+	actions map[string]string // actions taken on the source files and directories.
+	store   *quarantine.Store // store is the dated quarantine directory, created on the first removal.
+	can     *trash.Can        // can is the home trash directory, used by the trash option.
+	dry     tally             // dry is the sum of the items and bytes that the dry run would remove.
+	filter  *ign.Filter       // filter matches the paths using the .dupersignore files and the globs.
+	skips   skips             // skips are the number of files skipped by their size.
 }
 
 // Debugger prints the string to stdout whenever Config.Debug is true.
@@ -125,34 +153,36 @@ func (c *Config) StatSource() (bool, int, int, error) {
 }
 
 // Checksum the named file and save it to the bucket.
-// The saved checksum is a SHA256 hash.
+// The saved checksum is a SHA256 hash, that's stored together with the file size,
+// modification time and on Unix systems, the inode and device numbers.
 func (c *Config) Checksum(db *bolt.DB, name, bucket string) error {
 	if db == nil {
 		return bberr.ErrDatabaseNotOpen
 	}
-	if len(c.Compare) == 0 {
-		c.Compare = make(parse.Checksums)
-	}
 	c.Debugger("update: " + name)
-	// read file, exit if it fails
-	sum, err := parse.Read(name)
+	// stat and read file, exit if it fails
+	info, err := os.Stat(name)
 	if err != nil {
 		return err
 	}
-	if sum == [32]byte{} {
-		return nil
+	if err := c.checkSize(info.Size()); err != nil {
+		return fmt.Errorf("%w: %s", err, name)
+	}
+	sum, err := parse.Read(name)
+	if err != nil {
+		return err
 	}
 	if err = db.Update(func(tx *bolt.Tx) error {
 		// directory bucket
 		b1 := tx.Bucket([]byte(bucket))
-		return b1.Put([]byte(name), sum[:])
+		if err := b1.Put([]byte(name), record.New(sum, info).Bytes()); err != nil {
+			return err
+		}
+		return index.Add(tx, bucket, []byte(name))
 	}); err != nil {
 		return err
 	}
-	if c.Compare == nil {
-		c.Compare = make(parse.Checksums)
-	}
-	c.Compare[sum] = name
+	c.add(sum, name, info)
 	return nil
 }
 
@@ -163,6 +193,7 @@ func (c *Config) Clean(w io.Writer) error {
 
 // DelEmptyDirs removes all empty directories from c.Source.
 // Directories containing hidden system directories or files are not considered empty.
+// When c.DryRun is set, a directory is also empty when every item within it would be removed.
 func (c *Config) DelEmptyDirs(w io.Writer) error {
 	// checked against: https://github.com/bengarrett/dupers/blob/v1.1.0/pkg/dupe/dupe.go#L247
 	c.Debugger("remove all empty directories.")
@@ -173,10 +204,22 @@ func (c *Config) DelEmptyDirs(w io.Writer) error {
 	var count int
 	if err := godirwalk.Walk(path, &godirwalk.Options{
 		Unsorted: true,
-		Callback: func(_ string, _ *godirwalk.Dirent) error {
+		Callback: func(osPathname string, _ *godirwalk.Dirent) error {
+			if c.DryRun && c.Action(osPathname) != "" {
+				// the directory would already be removed
+				return godirwalk.SkipThis
+			}
 			return nil
 		},
 		PostChildrenCallback: func(osPathname string, _ *godirwalk.Dirent) error {
+			if c.DryRun {
+				empty, err := c.dryEmpty(osPathname)
+				if err != nil || !empty || osPathname == path {
+					return err
+				}
+				count++
+				return c.remove(osPathname)
+			}
 			s, err := godirwalk.NewScanner(osPathname)
 			if err != nil {
 				return err
@@ -193,8 +236,7 @@ func (c *Config) DelEmptyDirs(w io.Writer) error {
 			if osPathname == path {
 				return nil
 			}
-			count++
-			if err := os.Remove(osPathname); err != nil {
+			if err := c.remove(osPathname); err != nil {
 				return err
 			}
 			// everything okay
@@ -208,57 +250,49 @@ func (c *Config) DelEmptyDirs(w io.Writer) error {
 		printl(w, "No empty directories required removal.")
 		return nil
 	}
-	printf(w, "Removed %d empty directories in: '%s'\n", count, path)
+	verb := "Removed"
+	switch {
+	case c.DryRun && c.Trash:
+		verb = "Would trash"
+	case c.DryRun && c.Quarantine != "":
+		verb = "Would quarantine"
+	case c.DryRun:
+		verb = "Would remove"
+	case c.Trash:
+		verb = "Trashed"
+	case c.Quarantine != "":
+		verb = "Quarantined"
+	}
+	printf(w, "%s %d empty directories in: '%s'\n", verb, count, path)
 	return nil
 }
 
 // Print the results of a dupe request.
-func (c *Config) Print() (string, error) { //nolint:gocognit
+// Source files that do not share a size and a partial checksum with an item to compare are skipped,
+// while the remaining files are hashed using the worker pool set by c.Workers.
+func (c *Config) Print() (string, error) {
 	c.Debugger(fmt.Sprintf("print duplicate results\ncomparing %d sources against %d unique items to compare",
 		len(c.Sources), len(c.Compare)))
 
+	files, err := c.sourceFiles()
+	if err != nil {
+		return "", err
+	}
+	files = c.prune(files...)
+	sums, err := c.sums(files...)
+	if err != nil {
+		return "", err
+	}
 	w := new(bytes.Buffer)
 	finds := 0
-	for _, root := range c.Sources {
-		info, err := os.Stat(root)
-		if err != nil {
-			return "", err
-		}
-		if !info.IsDir() {
-			if err := c.printer(w, root); err != nil {
-				if errors.Is(err, ErrNoMatch) {
-					continue
-				}
-				return "", err
-			}
-			finds++
-			continue
-		}
-		c.Debugger("parse read path: " + root)
-		if err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
-			c.Debugger("walker: " + path)
-			if err != nil {
-				c.Debugger("stat sources error: " + err.Error())
-				return err
-			}
-			if root == path {
-				return nil
-			}
-			if err := SkipFS(true, false, true, d); err != nil {
-				ignore(err)
-				return nil
-			}
-			if err := c.printer(w, root); err != nil {
-				if errors.Is(err, ErrNoMatch) {
-					return nil
-				}
-				return err
+	for i, path := range files {
+		if err := c.printer(w, path, sums[i]); err != nil {
+			if errors.Is(err, ErrNoMatch) {
+				continue
 			}
-			finds++
-			return nil
-		}); err != nil {
-			continue
+			return "", err
 		}
+		finds++
 	}
 	if finds == 0 {
 		printl(w, color.Info.Sprint("\rNo duplicate files found.          "))
@@ -266,15 +300,43 @@ func (c *Config) Print() (string, error) { //nolint:gocognit
 	return w.String(), nil
 }
 
+// sourceFiles returns the files to check from c.Sources.
+func (c *Config) sourceFiles() ([]string, error) {
+	files := make([]string, 0, len(c.Sources))
+	for _, root := range c.Sources {
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			// the files of a source directory are added to c.Sources by WalkSource
+			c.Debugger("skip source directory: " + root)
+			continue
+		}
+		files = append(files, root)
+	}
+	return files, nil
+}
+
 // Deprecated: Use [DelDupeFiles] instead.
 func (c *Config) Remove() (string, error) {
 	return c.DelDupeFiles()
 }
 
 // DelDupeFiles duplicate files from the source directory.
+//
+// When c.Keep is set, the keep policy instead chooses the one file to keep from each group of duplicates,
+// and every other copy is removed, both inside and outside of the source directory.
 func (c *Config) DelDupeFiles() (string, error) {
 	// checked against: https://github.com/bengarrett/dupers/blob/v1.1.0/pkg/dupe/dupe.go#L364
 	c.Debugger("remove all duplicate files.")
+	var k Keep
+	if c.Keep != "" {
+		var err error
+		if k, err = ParseKeep(c.Keep); err != nil {
+			return "", err
+		}
+	}
 	w := new(bytes.Buffer)
 	if len(c.Sources) == 0 || len(c.Compare) == 0 {
 		printl(w, "No duplicate files to remove.          ")
@@ -283,6 +345,10 @@ func (c *Config) DelDupeFiles() (string, error) {
 	printl(w)
 	for i, path := range c.Sources {
 		c.Debugger(fmt.Sprintf(" %d. remove read: %s", i, path))
+		if c.Keep != "" && c.Action(path) != "" {
+			// removed by the keep policy of an earlier group
+			continue
+		}
 		stat, err := os.Stat(path)
 		if os.IsNotExist(err) {
 			c.Debugger("path is not exist: " + path)
@@ -295,11 +361,22 @@ func (c *Config) DelDupeFiles() (string, error) {
 		if err != nil {
 			return "", err
 		}
-		if one := c.lookupOne(checksum); one == "" {
+		// a source within a bucket is also stored, so it is not a match of itself
+		matches := c.matches(path, checksum)
+		if len(matches) == 0 {
 			continue
 		}
-		err = os.Remove(path)
-		printl(w, PrintRM(path, err))
+		if c.Keep != "" {
+			c.delKeep(w, k, checksum, append([]string{path}, matches...)...)
+			continue
+		}
+		keep := survivor(path, matches...)
+		if keep == "" {
+			c.Debugger("no surviving copy, skip: " + path)
+			continue
+		}
+		err = c.removeDupe(path, keep, checksum)
+		printl(w, c.printRM(path, err))
 	}
 	return w.String(), nil
 }
@@ -311,6 +388,7 @@ func (c *Config) Removes() ([]string, error) {
 
 // DelDirsExcept the directories from the source that do not contain unique MS-DOS or Windows programs.
 // The strings contains the path of any non-deletable files.
+// When c.DryRun is set, the confirmation prompt is skipped as nothing is deleted.
 func (c *Config) DelDirsExcept() ([]string, error) {
 	// checked against: https://github.com/bengarrett/dupers/blob/v1.1.0/pkg/dupe/dupe.go#L397
 	c.Debugger("removes directories that don't contain any DOS or Windows apps.")
@@ -331,26 +409,28 @@ func (c *Config) DelDirsExcept() ([]string, error) {
 	w := os.Stdout
 	if !c.Test {
 		printf(w, "%s %s\n", color.Secondary.Sprint("Target directory:"), color.Debug.Sprint(name))
-		printl(w, "Delete everything in the target directory, except for directories"+
-			"\ncontaining unique Windows or MS-DOS programs and assets?")
-		if input := printer.AskYN("Please confirm", c.Yes, printer.Nil); !input {
-			os.Exit(0)
+		if !c.DryRun {
+			printl(w, "Delete everything in the target directory, except for directories"+
+				"\ncontaining unique Windows or MS-DOS programs and assets?")
+			if input := printer.AskYN("Please confirm", c.Yes, printer.Nil); !input {
+				os.Exit(0)
+			}
 		}
 		printl(w)
 	}
-	return delDirsExcept(w, name, dirEntries)
+	return c.delDirsExcept(w, name, dirEntries)
 }
 
 // delDirsExcept directories that do not contain MS-DOS or Windows programs.
 // The strings contains the path of any undeletable files.
-func delDirsExcept(w io.Writer, name string, dirEntries []fs.DirEntry) ([]string, error) {
+func (c *Config) delDirsExcept(w io.Writer, name string, dirEntries []fs.DirEntry) ([]string, error) {
 	// checked against: https://github.com/bengarrett/dupers/blob/v1.1.0/pkg/dupe/dupe.go#L427
 	s := []string{}
 	for _, entry := range dirEntries {
 		path := filepath.Join(name, entry.Name())
 		if !entry.IsDir() {
-			err := os.Remove(path)
-			printl(w, PrintRM(path, err))
+			err := c.remove(path)
+			printl(w, c.printRM(path, err))
 			continue
 		}
 		foundExe, err := parse.Executable(path)
@@ -361,12 +441,11 @@ func delDirsExcept(w io.Writer, name string, dirEntries []fs.DirEntry) ([]string
 		if foundExe {
 			continue
 		}
-		err = os.RemoveAll(path)
-		printl(w, PrintRM(fmt.Sprintf("%s%s",
+		err = c.removeAll(path)
+		printl(w, c.printRM(fmt.Sprintf("%s%s",
 			path, string(filepath.Separator)), err))
 		if err != nil {
 			s = append(s, path)
-			continue
 		}
 	}
 	return s, nil
@@ -383,6 +462,9 @@ func (c *Config) Status() string {
 		s += color.Secondary.Sprint("Scanned ") +
 			color.Primary.Sprintf("%s files", p.Sprint(number.Decimal(c.Files)))
 	}
+	if skipped := c.Skipped(); skipped != "" {
+		s += color.Secondary.Sprint(", ") + color.Primary.Sprint(skipped)
+	}
 	if !c.Test {
 		t := c.Timer().Truncate(time.Millisecond)
 		s += color.Secondary.Sprint(", taking ") +
@@ -405,6 +487,10 @@ func (c *Config) WalkDirs(db *bolt.DB) error {
 	// walk through the directories provided
 	for _, bucket := range c.Buckets {
 		s := string(bucket)
+		if c.Option(s).ReadOnly {
+			c.Debugger("read-only bucket, use the stored checksums: " + s)
+			continue
+		}
 		c.Debugger("walkdir bucket: " + s)
 		if err := c.WalkDir(db, bucket); err != nil {
 			if errors.Is(errors.Unwrap(err), ErrPathNoFound) &&
@@ -414,6 +500,13 @@ func (c *Config) WalkDirs(db *bolt.DB) error {
 			}
 			return err
 		}
+		if !c.Option(s).Archives {
+			continue
+		}
+		c.Debugger("walk the archives in bucket: " + s)
+		if err := c.WalkArchiver(db, bucket); err != nil {
+			return err
+		}
 	}
 	// handle any items that exist in the database but not in the file system
 	// this would include items added using the `up+` archive scan command
@@ -469,14 +562,18 @@ func (c *Config) WalkSource() error {
 	return nil
 }
 
-// Match prints 'Found duplicate match'.
-func Match(path, match string) string {
-	if match == "" {
+// Match prints 'Found duplicate match' followed by every matching file.
+func Match(path string, matches ...string) string {
+	items := ""
+	for _, match := range matches {
+		items += matchItem(match)
+	}
+	if items == "" {
 		return ""
 	}
 	s := "\n"
 	s += color.Info.Sprint("Match") +
-		":" + "\t" + path + matchItem(match)
+		":" + "\t" + path + items
 	return s
 }
 
@@ -583,6 +680,72 @@ func SkipFile(name string) bool {
 	return strings.HasPrefix(name, macOSExtension)
 }
 
+// Filter returns the include and exclude globs, which also match the paths using the .dupersignore files.
+func (c *Config) Filter() *ign.Filter {
+	if c.filter == nil {
+		c.filter = &ign.Filter{Include: c.Include, Exclude: c.Exclude}
+	}
+	return c.filter
+}
+
+// ignored returns true if the path is excluded by the .dupersignore files or the globs.
+func ignored(m *ign.Matcher, path string, d fs.DirEntry) bool {
+	return m.Match(path, d.IsDir()) == ign.Skip
+}
+
+// skipped returns true if the path is excluded by the .dupersignore files or the globs,
+// or if it is a system or hidden directory or a system file that is not included by a pattern.
+func skipped(m *ign.Matcher, path string, d fs.DirEntry) bool {
+	switch m.Match(path, d.IsDir()) {
+	case ign.Skip:
+		return true
+	case ign.Keep:
+		return false
+	case ign.None:
+	}
+	if d.IsDir() {
+		return SkipDirectory(d.Name()) != nil
+	}
+	return SkipFile(d.Name())
+}
+
+// skipDir returns filepath.SkipDir when the entry is a directory, so WalkDir skips its content.
+func skipDir(d fs.DirEntry) error {
+	if d.IsDir() {
+		return filepath.SkipDir
+	}
+	return nil
+}
+
+// included returns the paths that are not excluded by the ignore rules of their bucket.
+func (c *Config) included(paths ...string) []string {
+	s := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if root := c.bucketOf(path); root != "" && c.Filter().Skip(root, path) {
+			c.Debugger("ignored match: " + path)
+			continue
+		}
+		s = append(s, path)
+	}
+	return s
+}
+
+// bucketOf returns the bucket that contains the named path, or an empty string if there is none.
+func (c *Config) bucketOf(path string) string {
+	const sep = string(filepath.Separator)
+	root := ""
+	for _, b := range c.Buckets {
+		s := string(b)
+		if s == "" || len(s) <= len(root) {
+			continue
+		}
+		if strings.HasPrefix(path, strings.TrimSuffix(s, sep)+sep) {
+			root = s
+		}
+	}
+	return root
+}
+
 // skipSelf returns true if the path exists in skip.
 func skipSelf(path string, skip ...string) bool {
 	return slices.Contains(skip, path)
@@ -593,6 +756,16 @@ func Print(quiet, exact bool, term string, m *database.Matches) string {
 	return parse.Print(quiet, exact, term, m)
 }
 
+// PrintPattern prints the results of the database pattern comparisons.
+func PrintPattern(quiet bool, p *database.Pattern, m *database.Matches) string {
+	return parse.PrintPattern(quiet, p, m)
+}
+
+// PrintRanked prints the results of the database fuzzy comparisons in ranked order.
+func PrintRanked(quiet, exact bool, term string, r database.Ranks) string {
+	return parse.PrintRanked(quiet, exact, term, r)
+}
+
 // Bucket returns the named string as a Bucket type.
 func Bucket(name string) parse.Bucket {
 	return parse.Bucket(name)
@@ -620,6 +793,7 @@ func (c *Config) WalkArchiver(db *bolt.DB, bucket parse.Bucket) error {
 		return err
 	}
 	// walk the root directory of the bucket
+	m := c.Filter().Matcher(root)
 	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
 		c.Debugger("walk file: " + path)
 		if err != nil {
@@ -632,6 +806,9 @@ func (c *Config) WalkArchiver(db *bolt.DB, bucket parse.Bucket) error {
 		if root == path || skipSelf(path, skip...) {
 			return nil
 		}
+		if ignored(m, path, d) {
+			return c.walkDebug(" -skip ignored", skipDir(d))
+		}
 		if err := SkipFS(false, true, true, d); err != nil {
 			ignore(err)
 			return nil
@@ -659,7 +836,7 @@ func (c *Config) Read7Zip(db *bolt.DB, bucket parse.Bucket, name string) error {
 	}()
 	cnt := 0
 	for _, f := range r.File {
-		if f.FileInfo().IsDir() {
+		if f.FileInfo().IsDir() || !c.sized(f.FileInfo().Size()) {
 			continue
 		}
 		path := filepath.Join(name, f.Name)
@@ -682,7 +859,7 @@ func (c *Config) Read7Zip(db *bolt.DB, bucket parse.Bucket, name string) error {
 		}
 		var sum parse.Checksum
 		copy(sum[:], h.Sum(nil))
-		if err := c.update(db, bucket, path, sum); err != nil {
+		if err := c.update(db, bucket, path, sum, f.FileInfo()); err != nil {
 			return err
 		}
 	}
@@ -757,7 +934,7 @@ func (c *Config) Read(db *bolt.DB, bucket parse.Bucket, name, mimeExt string) er
 
 // processArchiveFile processes an individual file from an archive.
 func (c *Config) processArchiveFile(db *bolt.DB, bucket parse.Bucket, name string, fileInfo archives.FileInfo) error {
-	if fileInfo.IsDir() {
+	if fileInfo.IsDir() || !c.sized(fileInfo.Size()) {
 		return nil
 	}
 
@@ -791,55 +968,57 @@ func (c *Config) processArchiveFile(db *bolt.DB, bucket parse.Bucket, name strin
 	copy(sum[:], h.Sum(nil))
 
 	// Update database
-	if err := c.update(db, bucket, fullPath, sum); err != nil {
+	if err := c.update(db, bucket, fullPath, sum, fileInfo); err != nil {
 		printer.Stderr(err)
 	}
 
 	return nil
 }
 
-func (c *Config) walkDir(db *bolt.DB, root string, skip []string) error {
+// inodes returns the paths of the items in the bucket that have a stored inode and device.
+func (c *Config) inodes(db *bolt.DB, root string) (map[[2]uint64]string, error) {
 	if db == nil {
-		return bberr.ErrDatabaseNotOpen
+		return nil, bberr.ErrDatabaseNotOpen
 	}
-	c.Debugger("walk directory: " + root)
-	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
-		if c.Debug {
-			s := "walk file"
-			if d.IsDir() {
-				s = "walk subdirectory"
-			}
-			c.Debugger(fmt.Sprintf("%s: %s", s, path))
-		}
-		if err != nil {
-			if errors.Is(err, fs.ErrPermission) {
-				return nil
-			}
-			c.Debugger(errRead + err.Error())
-			return err
-		}
-		if path == root || skipSelf(path, skip...) {
-			return c.walkDebug(" -skip self", nil)
-		}
-		if err := SkipFS(false, true, true, d); err != nil {
-			return c.walkDebug(" -skip directory or system file", err)
-		}
-		c.Files++
-		if err := c.walkCompare(db, root, path); err != nil {
-			if errors.Is(err, ErrPathExist) {
-				return nil
-			}
-			return err
+	records, err := database.ListRecords(db, root)
+	if err != nil {
+		if errors.Is(err, bberr.ErrBucketNotFound) {
+			return nil, nil
 		}
-		_, _ = fmt.Fprint(os.Stdout, PrintWalk(false, c))
-		if err := c.Checksum(db, path, root); err != nil {
-			return err
+		return nil, err
+	}
+	inodes := make(map[[2]uint64]string, len(records))
+	for path, r := range records {
+		if id, ok := r.ID(); ok {
+			inodes[id] = string(path)
 		}
-		return nil
-	})
+	}
+	c.Debugger(fmt.Sprintf("bucket items with inodes: %d", len(inodes)))
+	return inodes, nil
+}
+
+// moved looks up the inode of the named file for an item in the bucket that has been moved or renamed.
+// If found, the returned hash contains the stored checksum and the original path of the item.
+func (c *Config) moved(db *bolt.DB, root, path string, info fs.FileInfo, inodes map[[2]uint64]string) (hash, bool) {
+	id, ok := record.New(parse.Checksum{}, info).ID()
+	if !ok {
+		return hash{}, false
+	}
+	prev, ok := inodes[id]
+	if !ok || prev == path {
+		return hash{}, false
+	}
+	r, err := c.stored(db, root, prev)
+	if err != nil || !r.Same(info) {
+		return hash{}, false
+	}
+	c.Debugger(fmt.Sprintf(" - moved: %s -> %s", prev, path))
+	inodes[id] = path
+	return hash{path: path, prev: prev, sum: r.Sum, info: info, save: true}, true
 }
 
 func (c *Config) statSources(root string) error {
+	m := c.Filter().Matcher(root)
 	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
 		c.Debugger(path)
 		if err != nil {
@@ -849,10 +1028,16 @@ func (c *Config) statSources(root string) error {
 		if root == path {
 			return nil
 		}
+		if ignored(m, path, d) {
+			return c.walkDebug(" -skip ignored", skipDir(d))
+		}
 		if err := SkipFS(true, false, true, d); err != nil {
 			ignore(err)
 			return nil
 		}
+		if info, err := d.Info(); err == nil && !c.sized(info.Size()) {
+			return c.walkDebug(" -skip by size", nil)
+		}
 		c.Sources = append(c.Sources, path)
 		return nil
 	})
@@ -871,8 +1056,10 @@ func (c *Config) create(db *bolt.DB, bucket parse.Bucket) error {
 	}
 	return db.Update(func(tx *bolt.Tx) error {
 		if b := tx.Bucket([]byte(bucket)); b == nil {
-			_, err := tx.CreateBucket([]byte(bucket))
-			return err
+			if _, err := tx.CreateBucket([]byte(bucket)); err != nil {
+				return err
+			}
+			return index.Create(tx, string(bucket))
 		}
 		return nil
 	})
@@ -915,19 +1102,28 @@ func (c *Config) init(db *bolt.DB) error {
 	return nil
 }
 
-// lookup the checksum value in c.compare and return the file path.
-func (c *Config) lookupOne(sum parse.Checksum) string {
-	// checked against: https://github.com/bengarrett/dupers/blob/v1.1.0/pkg/dupe/dupe.go#L674
-	if len(c.Compare) == 0 {
-		c.Compare = make(parse.Checksums)
-	}
+// lookup the checksum value in c.Index and return every file path that shares it.
+func (c *Config) lookup(sum parse.Checksum) []string {
 	c.Debugger(fmt.Sprintf("look up checksum in the compare data, %d items total: %x",
 		len(c.Compare), sum))
-	if f := c.Compare[sum]; f != "" {
-		c.Debugger("lookupOne match: " + f)
-		return f
+	paths := c.included(c.Lookup(sum)...)
+	if len(paths) > 0 {
+		c.Debugger("lookup matches: " + strings.Join(paths, ", "))
 	}
-	return ""
+	return paths
+}
+
+// matches returns the file paths that share the checksum, excluding the named path.
+func (c *Config) matches(path string, sum parse.Checksum) []string {
+	paths := c.lookup(sum)
+	matches := make([]string, 0, len(paths))
+	for _, match := range paths {
+		if match == path {
+			continue
+		}
+		matches = append(matches, match)
+	}
+	return matches
 }
 
 // skipFiles returns the value of c.sources as strings.
@@ -937,7 +1133,9 @@ func (c *Config) skipFiles() []string {
 	return files
 }
 
-// walkCompare walks the root directory and adds the checksums of the files to c.compare.
+// walkCompare looks up the path in the root bucket and adds the stored checksum to c.compare.
+// ErrPathExist is returned when the stored size and modification time match the file,
+// so the file is unchanged and does not need to be hashed again.
 func (c *Config) walkCompare(db *bolt.DB, root, path string) error {
 	if db == nil {
 		return bberr.ErrDatabaseNotOpen
@@ -945,24 +1143,46 @@ func (c *Config) walkCompare(db *bolt.DB, root, path string) error {
 	if c.Compare == nil {
 		c.Compare = make(parse.Checksums)
 	}
-	return db.View(func(tx *bolt.Tx) error {
-		if !c.Test && !c.Quiet && !c.Debug {
-			_, _ = fmt.Fprint(os.Stdout, printer.Status(c.Files, -1, printer.Scan))
-		}
+	if !c.Test && !c.Quiet && !c.Debug {
+		_, _ = fmt.Fprint(os.Stdout, printer.Status(c.Files, -1, printer.Scan))
+	}
+	r, err := c.stored(db, root, path)
+	if err != nil {
+		return err
+	}
+	c.Debugger(fmt.Sprintf(" - %d/%d items: %x", len(c.Compare), c.Files, r.Sum))
+	info, err := os.Stat(path)
+	if err != nil || !r.Same(info) {
+		c.Debugger(" - changed or missing stat data: " + path)
+		return nil
+	}
+	c.add(r.Sum, path, info)
+	return ErrPathExist
+}
+
+// stored returns the record of the path saved in the root bucket.
+// An empty record is returned when the path is not in the bucket or the value is invalid.
+func (c *Config) stored(db *bolt.DB, root, path string) (record.Record, error) {
+	if db == nil {
+		return record.Record{}, bberr.ErrDatabaseNotOpen
+	}
+	var r record.Record
+	err := db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(root))
 		if b == nil {
 			return ErrNoNamedBucket
 		}
 		h := b.Get([]byte(path))
-		c.Debugger(fmt.Sprintf(" - %d/%d items: %x", len(c.Compare), c.Files, h))
-		if len(h) > 0 {
-			var sum parse.Checksum
-			copy(sum[:], h)
-			c.Compare[sum] = path
-			return ErrPathExist
+		if len(h) == 0 {
+			return nil
+		}
+		var err error
+		if r, err = record.Decode(h); err != nil {
+			r = record.Record{}
 		}
 		return nil
 	})
+	return r, err
 }
 
 func (c *Config) walkDebug(s string, err error) error {
@@ -1047,19 +1267,15 @@ func (c *Config) listItems(db *bolt.DB, bucket string) error {
 	return nil
 }
 
-func (c *Config) printer(w io.Writer, path string) error {
-	sum, err := parse.Read(path)
-	if err != nil {
-		return err
-	}
-	l := c.lookupOne(sum)
-	if l == "" {
+func (c *Config) printer(w io.Writer, path string, sum parse.Checksum) error {
+	if info, err := os.Stat(path); err == nil && c.checkSize(info.Size()) != nil {
 		return ErrNoMatch
 	}
-	if l == path {
+	matches := c.matches(path, sum)
+	if len(matches) == 0 {
 		return ErrNoMatch
 	}
-	printl(w, Match(path, l))
+	printl(w, Match(path, matches...))
 	return nil
 }
 
@@ -1081,7 +1297,7 @@ func (c *Config) statSource() (bool, int, error) {
 		}
 	}
 	if !stat.IsDir() {
-		if stat.Size() == 0 {
+		if stat.Size() == 0 && !c.Empty {
 			return false, 1, ErrFileEmpty
 		}
 		return false, 1, nil
@@ -1091,6 +1307,7 @@ func (c *Config) statSource() (bool, int, error) {
 	}
 	files := 0
 	root := name
+	m := c.Filter().Matcher(root)
 	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
 		c.Debugger("counting: " + path)
 		if err != nil {
@@ -1101,6 +1318,9 @@ func (c *Config) statSource() (bool, int, error) {
 		if root == path {
 			return nil
 		}
+		if ignored(m, path, d) {
+			return skipDir(d)
+		}
 		if err := SkipFS(true, false, true, d); err != nil {
 			ignore(err)
 			return nil
@@ -1127,6 +1347,7 @@ func (c *Config) walkBucket(bucket parse.Bucket, buckets int) (int, error) {
 		return 0, err
 	}
 	c.Debugger("walking bucket: " + root)
+	m := c.Filter().Matcher(root)
 	return buckets, filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			c.Debugger(errRead + err.Error())
@@ -1136,6 +1357,9 @@ func (c *Config) walkBucket(bucket parse.Bucket, buckets int) (int, error) {
 			return nil
 		}
 		c.Debugger("walking bucket item: " + path)
+		if skipped(m, path, d) {
+			return skipDir(d)
+		}
 		if err := SkipFS(true, false, true, d); err != nil {
 			ignore(err)
 			return nil
@@ -1157,24 +1381,32 @@ func (c *Config) readRecover(archive string) {
 	}
 }
 
-// update saves the checksum and path values to the bucket.
-func (c *Config) update(db *bolt.DB, bucket parse.Bucket, path string, sum parse.Checksum) error {
+// update saves the checksum, path and any file stat values to the bucket.
+func (c *Config) update(db *bolt.DB, bucket parse.Bucket, path string, sum parse.Checksum, info fs.FileInfo) error {
 	if db == nil {
 		return bberr.ErrDatabaseNotOpen
 	}
-	if len(c.Compare) == 0 {
-		c.Compare = make(parse.Checksums)
-	}
 	c.Debugger("update archiver: " + path)
 	if err := db.Update(func(tx *bolt.Tx) error {
 		b1 := tx.Bucket([]byte(bucket))
 		if b1 == nil {
 			return bberr.ErrBucketNotFound
 		}
-		return b1.Put([]byte(path), sum[:])
+		if err := b1.Put([]byte(path), record.New(sum, info).Bytes()); err != nil {
+			return err
+		}
+		return index.Add(tx, string(bucket), []byte(path))
 	}); err != nil {
 		return err
 	}
-	c.Compare[sum] = path
+	c.add(sum, path, info)
 	return nil
 }
+
+// add the checksum and path to the compare scanners, together with the file size when the stat info is known.
+func (c *Config) add(sum parse.Checksum, path string, info fs.FileInfo) {
+	c.Add(sum, path)
+	if info != nil {
+		c.SetSize(path, info.Size())
+	}
+}
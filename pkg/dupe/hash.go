@@ -0,0 +1,341 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+package dupe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/bengarrett/dupers/pkg/database/index"
+	"github.com/bengarrett/dupers/pkg/database/record"
+	ign "github.com/bengarrett/dupers/pkg/dupe/ignore"
+	"github.com/bengarrett/dupers/pkg/dupe/parse"
+	bolt "go.etcd.io/bbolt"
+	bberr "go.etcd.io/bbolt/errors"
+)
+
+// batchSize is the maximum number of items saved to a bucket in a single transaction.
+const batchSize = 1000
+
+// hash is a walked file that has either been hashed or has a stored checksum.
+type hash struct {
+	path string         // path of the file.
+	prev string         // prev is the stored path of a moved or renamed file.
+	sum  parse.Checksum // sum is the SHA256 checksum of the file.
+	info fs.FileInfo    // info is the file stat data.
+	save bool           // save the checksum to the bucket.
+	err  error          // err is any error from hashing the file.
+}
+
+// workers returns the number of files that can be hashed at the same time.
+func (c *Config) workers() int {
+	if c.Workers < 1 {
+		return runtime.NumCPU()
+	}
+	return c.Workers
+}
+
+// walkDir walks the root directory and saves the checksums of new and changed files to the bucket.
+//
+// The walk is a pipeline of a single walker, multiple hashing workers and a single writer.
+// The writer is the only stage to update the database, c.Compare and the progress count.
+func (c *Config) walkDir(db *bolt.DB, root string, skip []string) error {
+	if db == nil {
+		return bberr.ErrDatabaseNotOpen
+	}
+	c.Debugger("walk directory: " + root)
+	inodes, err := c.inodes(db, root)
+	if err != nil {
+		return err
+	}
+	m := c.Filter().Matcher(root)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	jobs, hashes := make(chan hash), make(chan hash)
+	var walker, workers sync.WaitGroup
+	var walkErr error
+	walker.Go(func() {
+		defer close(jobs)
+		walkErr = c.walker(ctx, db, m, root, skip, inodes, jobs, hashes)
+	})
+	n := c.workers()
+	c.Debugger(fmt.Sprintf("hashing workers: %d", n))
+	for range n {
+		workers.Go(func() {
+			hasher(jobs, hashes)
+		})
+	}
+	go func() {
+		walker.Wait()
+		workers.Wait()
+		close(hashes)
+	}()
+	if err := c.writer(db, root, hashes, cancel); err != nil {
+		return err
+	}
+	return walkErr
+}
+
+// walker walks the root directory and sends every file to either the hashing workers or the writer.
+func (c *Config) walker(ctx context.Context, db *bolt.DB, m *ign.Matcher, root string, skip []string,
+	inodes map[[2]uint64]string, jobs, hashes chan<- hash,
+) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if c.Debug {
+			s := "walk file"
+			if d != nil && d.IsDir() {
+				s = "walk subdirectory"
+			}
+			c.Debugger(fmt.Sprintf("%s: %s", s, path))
+		}
+		if err != nil {
+			if errors.Is(err, fs.ErrPermission) {
+				return nil
+			}
+			c.Debugger(errRead + err.Error())
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if path == root || skipSelf(path, skip...) {
+			return c.walkDebug(" -skip self", nil)
+		}
+		if skipped(m, path, d) {
+			return c.walkDebug(" -skip ignored, system or hidden item", skipDir(d))
+		}
+		if d.IsDir() {
+			return c.walkDebug(" -walk subdirectory", nil)
+		}
+		if !d.Type().IsRegular() {
+			return c.walkDebug(" -skip non-regular file", nil)
+		}
+		return c.queue(db, root, path, inodes, jobs, hashes)
+	})
+}
+
+// queue sends the named file to the writer when it is unchanged or has been moved,
+// otherwise the file is sent to the workers to be hashed.
+func (c *Config) queue(db *bolt.DB, root, path string,
+	inodes map[[2]uint64]string, jobs, hashes chan<- hash,
+) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !c.sized(info.Size()) {
+		return c.walkDebug(" -skip by size", nil)
+	}
+	r, err := c.stored(db, root, path)
+	if err != nil {
+		return err
+	}
+	c.Debugger(fmt.Sprintf(" - stored item: %x", r.Sum))
+	if r.Same(info) {
+		hashes <- hash{path: path, sum: r.Sum, info: info}
+		return nil
+	}
+	if h, ok := c.moved(db, root, path, info, inodes); ok {
+		hashes <- h
+		return nil
+	}
+	c.Debugger(" - changed or missing stat data: " + path)
+	jobs <- hash{path: path, info: info}
+	return nil
+}
+
+// hasher reads and hashes the files received from jobs.
+func hasher(jobs <-chan hash, hashes chan<- hash) {
+	for h := range jobs {
+		h.sum, h.err = parse.Read(h.path)
+		h.save = true
+		hashes <- h
+	}
+}
+
+// writer receives the walked files, saves any new checksums to the root bucket in batches
+// and prints the scanning progress.
+// Any error cancels the walker, but the hashes channel is always drained.
+func (c *Config) writer(db *bolt.DB, root string, hashes <-chan hash, cancel context.CancelFunc) error {
+	var err error
+	batch := make([]hash, 0, batchSize)
+	for h := range hashes {
+		if err != nil {
+			continue
+		}
+		c.Files++
+		_, _ = fmt.Fprint(os.Stdout, PrintWalk(false, c))
+		if h.err != nil {
+			err = h.err
+			cancel()
+			continue
+		}
+		if !h.save {
+			c.add(h.sum, h.path, h.info)
+			continue
+		}
+		if h.sum == [32]byte{} {
+			continue
+		}
+		batch = append(batch, h)
+		if len(batch) < batchSize {
+			continue
+		}
+		if err = c.save(db, root, batch); err != nil {
+			cancel()
+		}
+		batch = batch[:0]
+	}
+	if err != nil {
+		return err
+	}
+	return c.save(db, root, batch)
+}
+
+// save writes the batch of checksums and file stat data to the root bucket in a single transaction.
+// The stored items of moved files are removed when the original path no longer exists.
+func (c *Config) save(db *bolt.DB, root string, batch []hash) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(root))
+		if b == nil {
+			return bberr.ErrBucketNotFound
+		}
+		for _, h := range batch {
+			if err := b.Put([]byte(h.path), record.New(h.sum, h.info).Bytes()); err != nil {
+				return err
+			}
+			if err := index.Add(tx, root, []byte(h.path)); err != nil {
+				return err
+			}
+			if h.prev == "" {
+				continue
+			}
+			if _, err := os.Stat(h.prev); errors.Is(err, fs.ErrNotExist) {
+				if err := b.Delete([]byte(h.prev)); err != nil {
+					return err
+				}
+				if err := index.Delete(tx, root, []byte(h.prev)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	for _, h := range batch {
+		c.add(h.sum, h.path, h.info)
+	}
+	c.Debugger(fmt.Sprintf("saved %d items to the bucket: %s", len(batch), root))
+	return nil
+}
+
+// sums reads and hashes the named files using the hashing workers.
+// The checksums are returned in the same order as the names.
+func (c *Config) sums(names ...string) ([]parse.Checksum, error) {
+	sums, errs := make([]parse.Checksum, len(names)), make([]error, len(names))
+	jobs := make(chan int)
+	var workers sync.WaitGroup
+	for range min(c.workers(), len(names)) {
+		workers.Go(func() {
+			for i := range jobs {
+				sums[i], errs[i] = parse.Read(names[i])
+			}
+		})
+	}
+	for i := range names {
+		jobs <- i
+	}
+	close(jobs)
+	workers.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sums, nil
+}
+
+// prune returns the named files that share both a file size and a partial checksum with an item to compare.
+// Only these files could be duplicates that need a full SHA256 hash.
+// Every named file is returned when the size of an item to compare is unknown.
+func (c *Config) prune(names ...string) []string {
+	sizes, ok := c.compareSizes()
+	if !ok {
+		c.Debugger("skip size pruning, not every item to compare has a known size")
+		return names
+	}
+	parts := make(map[string]parse.Checksum)
+	keep := make([]string, 0, len(names))
+	for _, name := range names {
+		info, err := os.Stat(name)
+		if err != nil {
+			// the error is reported by the full hash
+			keep = append(keep, name)
+			continue
+		}
+		paths, ok := sizes[info.Size()]
+		if !ok {
+			continue
+		}
+		if collide(name, info.Size(), paths, parts) {
+			keep = append(keep, name)
+		}
+	}
+	c.Debugger(fmt.Sprintf("size pruning kept %d of %d files to hash", len(keep), len(names)))
+	return keep
+}
+
+// compareSizes returns the paths of the items to compare grouped by their file size.
+// The bool is false if any item to compare has an unknown size.
+func (c *Config) compareSizes() (map[int64][]string, bool) {
+	sizes := make(map[int64][]string)
+	for sum := range c.Compare {
+		paths, ok := c.Index[sum]
+		if !ok {
+			return nil, false
+		}
+		for _, path := range paths {
+			size, ok := c.Sizes[path]
+			if !ok {
+				return nil, false
+			}
+			sizes[size] = append(sizes[size], string(path))
+		}
+	}
+	return sizes, true
+}
+
+// collide returns true when the partial checksum of the named file matches any of the paths.
+// Small files and any paths that cannot be read, such as items within archives, always collide.
+// The parts map caches the partial checksums of the paths.
+func collide(name string, size int64, paths []string, parts map[string]parse.Checksum) bool {
+	if size <= parse.PartSize*2 {
+		return true
+	}
+	sum, err := parse.ReadPartial(name)
+	if err != nil {
+		return true
+	}
+	for _, path := range paths {
+		part, ok := parts[path]
+		if !ok {
+			if part, err = parse.ReadPartial(path); err != nil {
+				return true
+			}
+			parts[path] = part
+		}
+		if part == sum {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,266 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+
+// Package ignore matches paths against .dupersignore files and the include and exclude glob filters.
+// The .dupersignore files use the gitignore pattern syntax and apply to the directory they are in
+// and its subdirectories, with the patterns of the deeper files taking priority.
+package ignore
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/bengarrett/dupers/internal/glob"
+)
+
+// Name is the filename of the ignore files that are read from the root directory and its subdirectories.
+const Name = ".dupersignore"
+
+var ErrPattern = errors.New("glob pattern is invalid")
+
+// Result of a match.
+type Result int
+
+const (
+	None Result = iota // None means no pattern matched the path.
+	Skip               // Skip means the path is excluded.
+	Keep               // Keep means the path is included, even when it would be skipped by default.
+)
+
+// Filter holds the include and exclude globs that are used by every root directory.
+// The globs use the same syntax as the .dupersignore patterns.
+type Filter struct {
+	Include []string // Include globs, when used only the matching files are kept.
+	Exclude []string // Exclude globs of the files and directories to skip.
+
+	roots map[string]*Matcher // roots are the matchers of the root directories.
+}
+
+// Check returns an error if any of the include or exclude globs are invalid.
+func (f *Filter) Check() error {
+	if f == nil {
+		return nil
+	}
+	for _, s := range append(append([]string{}, f.Include...), f.Exclude...) {
+		if _, err := path.Match(strings.TrimPrefix(s, "!"), ""); err != nil {
+			return fmt.Errorf("%w: %s", ErrPattern, s)
+		}
+	}
+	return nil
+}
+
+// Matcher returns the matcher of the root directory.
+func (f *Filter) Matcher(root string) *Matcher {
+	if f == nil {
+		return New(root, nil, nil)
+	}
+	root = filepath.Clean(root)
+	if m, ok := f.roots[root]; ok {
+		return m
+	}
+	if f.roots == nil {
+		f.roots = make(map[string]*Matcher)
+	}
+	m := New(root, f.Include, f.Exclude)
+	f.roots[root] = m
+	return m
+}
+
+// Skip returns true when the named path within the root directory is excluded.
+func (f *Filter) Skip(root, name string) bool {
+	return f.Matcher(root).Match(name, false) == Skip
+}
+
+// Matcher matches the paths within a root directory.
+type Matcher struct {
+	root    string
+	include []rule
+	exclude []rule
+	files   map[string][]rule // files are the rules of the .dupersignore files, keyed by their directory.
+	dirs    map[string]Result // dirs are the results of the directories that have been matched.
+}
+
+type rule struct {
+	re       *regexp.Regexp
+	negate   bool // negate includes the matching paths.
+	dirOnly  bool // dirOnly only matches directories.
+	anchored bool // anchored matches the path relative to the directory of the pattern, instead of the name.
+}
+
+// New returns a matcher of the root directory using the include and exclude globs.
+func New(root string, include, exclude []string) *Matcher {
+	m := &Matcher{
+		root:  filepath.Clean(root),
+		files: make(map[string][]rule),
+		dirs:  make(map[string]Result),
+	}
+	for _, s := range include {
+		if r, ok := compile(s); ok {
+			m.include = append(m.include, r)
+		}
+	}
+	for _, s := range exclude {
+		if r, ok := compile(s); ok {
+			m.exclude = append(m.exclude, r)
+		}
+	}
+	return m
+}
+
+// Match the named path within the root directory.
+// A path within a skipped directory is also skipped, and the .dupersignore files are always skipped.
+// Paths outside of the root directory return None.
+func (m *Matcher) Match(name string, dir bool) Result {
+	if m == nil {
+		return None
+	}
+	rel, err := filepath.Rel(m.root, name)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return None
+	}
+	rel = filepath.ToSlash(rel)
+	parts := strings.Split(rel, "/")
+	for i := 1; i < len(parts); i++ {
+		if m.dir(strings.Join(parts[:i], "/")) == Skip {
+			return Skip
+		}
+	}
+	if dir {
+		return m.dir(rel)
+	}
+	return m.match(rel, false)
+}
+
+// dir returns the cached result of the relative directory.
+func (m *Matcher) dir(rel string) Result {
+	if r, ok := m.dirs[rel]; ok {
+		return r
+	}
+	r := m.match(rel, true)
+	m.dirs[rel] = r
+	return r
+}
+
+// match the relative path using the .dupersignore files and then the globs.
+func (m *Matcher) match(rel string, dir bool) Result {
+	if !dir && path.Base(rel) == Name {
+		return Skip
+	}
+	res := None
+	for _, d := range parents(rel) {
+		sub := rel
+		if d != "." {
+			sub = strings.TrimPrefix(rel, d+"/")
+		}
+		for _, r := range m.load(d) {
+			if r.match(sub, dir) {
+				res = Skip
+				if r.negate {
+					res = Keep
+				}
+			}
+		}
+	}
+	for _, r := range m.exclude {
+		if r.match(rel, dir) {
+			if r.negate {
+				res = Keep
+				continue
+			}
+			return Skip
+		}
+	}
+	if dir || len(m.include) == 0 || res == Skip {
+		return res
+	}
+	for _, r := range m.include {
+		if r.match(rel, dir) {
+			return Keep
+		}
+	}
+	return Skip
+}
+
+// parents returns the relative directories from the root to the parent of the path.
+func parents(rel string) []string {
+	dirs := []string{"."}
+	parts := strings.Split(rel, "/")
+	for i := 1; i < len(parts); i++ {
+		dirs = append(dirs, strings.Join(parts[:i], "/"))
+	}
+	return dirs
+}
+
+// load returns the rules of the .dupersignore file within the relative directory.
+// A missing or unreadable file has no rules.
+func (m *Matcher) load(rel string) []rule {
+	if rules, ok := m.files[rel]; ok {
+		return rules
+	}
+	rules := []rule{}
+	f, err := os.Open(filepath.Join(m.root, filepath.FromSlash(rel), Name))
+	if err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if r, ok := compile(scanner.Text()); ok {
+				rules = append(rules, r)
+			}
+		}
+	}
+	m.files[rel] = rules
+	return rules
+}
+
+// match returns true when the rule matches the relative path.
+func (r rule) match(rel string, dir bool) bool {
+	if r.dirOnly && !dir {
+		return false
+	}
+	if r.anchored {
+		return r.re.MatchString(rel)
+	}
+	return r.re.MatchString(path.Base(rel))
+}
+
+// compile the gitignore pattern into a rule.
+// It returns false for blank lines, comments and patterns that cannot be used.
+func compile(line string) (rule, bool) {
+	s := strings.TrimRight(line, " \t\r")
+	if strings.HasSuffix(s, `\`) && len(s) < len(line) {
+		// an escaped trailing space
+		s += " "
+	}
+	if s == "" || strings.HasPrefix(s, "#") {
+		return rule{}, false
+	}
+	r := rule{}
+	if strings.HasPrefix(s, "!") {
+		r.negate = true
+		s = s[1:]
+	} else if strings.HasPrefix(s, `\!`) || strings.HasPrefix(s, `\#`) {
+		s = s[1:]
+	}
+	if strings.HasSuffix(s, "/") {
+		r.dirOnly = true
+		s = strings.TrimRight(s, "/")
+	}
+	if strings.Contains(s, "/") {
+		r.anchored = true
+		s = strings.TrimPrefix(s, "/")
+	}
+	if s == "" {
+		return rule{}, false
+	}
+	re, err := regexp.Compile("^" + glob.Expr(s) + "$")
+	if err != nil {
+		re = regexp.MustCompile("^" + regexp.QuoteMeta(s) + "$")
+	}
+	r.re = re
+	return r, true
+}
@@ -0,0 +1,85 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+package ignore_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bengarrett/dupers/internal/mock"
+	"github.com/bengarrett/dupers/pkg/dupe/ignore"
+	"github.com/nalgeon/be"
+)
+
+func TestFilter_Check(t *testing.T) {
+	f := ignore.Filter{Include: []string{"*.jpg", "photos/**"}, Exclude: []string{"!.*", "build/"}}
+	be.Err(t, f.Check(), nil)
+	f.Exclude = append(f.Exclude, "[")
+	be.Err(t, f.Check(), ignore.ErrPattern)
+}
+
+func TestMatcher_Match(t *testing.T) {
+	root := t.TempDir()
+	write := func(dir, rules string) {
+		t.Helper()
+		err := os.MkdirAll(filepath.Join(root, dir), mock.PrivateDir)
+		be.Err(t, err, nil)
+		err = os.WriteFile(filepath.Join(root, dir, ignore.Name), []byte(rules), mock.PrivateFile)
+		be.Err(t, err, nil)
+	}
+	write(".", "# build output\nbuild/\n*.log\n!keep.log\n/top.txt\ndocs/**/*.tmp\n!.config/\n")
+	write("sub", "!*.log\nsecret.txt\n")
+	m := ignore.New(root, nil, nil)
+	tests := []struct {
+		name string
+		dir  bool
+		want ignore.Result
+	}{
+		{"file.txt", false, ignore.None},
+		{ignore.Name, false, ignore.Skip},
+		{"build", true, ignore.Skip},
+		{"build", false, ignore.None},
+		{filepath.Join("build", "app.exe"), false, ignore.Skip},
+		{"error.log", false, ignore.Skip},
+		{"keep.log", false, ignore.Keep},
+		{"top.txt", false, ignore.Skip},
+		{filepath.Join("other", "top.txt"), false, ignore.None},
+		{filepath.Join("docs", "a.tmp"), false, ignore.Skip},
+		{filepath.Join("docs", "a", "b", "c.tmp"), false, ignore.Skip},
+		{".config", true, ignore.Keep},
+		{filepath.Join("sub", "error.log"), false, ignore.Keep},
+		{filepath.Join("sub", "deep", "secret.txt"), false, ignore.Skip},
+		{"secret.txt", false, ignore.None},
+		{filepath.Join("..", "outside.log"), false, ignore.None},
+	}
+	for _, tt := range tests {
+		got := m.Match(filepath.Join(root, tt.name), tt.dir)
+		be.Equal(t, got, tt.want)
+	}
+}
+
+func TestFilter_Skip(t *testing.T) {
+	root := t.TempDir()
+	f := ignore.Filter{Include: []string{"*.jpg"}, Exclude: []string{"thumbs/", "!.*"}}
+	tests := []struct {
+		name string
+		dir  bool
+		want ignore.Result
+	}{
+		{"photo.jpg", false, ignore.Keep},
+		{"photo.png", false, ignore.Skip},
+		{"album", true, ignore.None},
+		{filepath.Join("thumbs", "photo.jpg"), false, ignore.Skip},
+		{".hidden", true, ignore.Keep},
+	}
+	m := f.Matcher(root)
+	for _, tt := range tests {
+		got := m.Match(filepath.Join(root, tt.name), tt.dir)
+		be.Equal(t, got, tt.want)
+	}
+	be.True(t, f.Matcher(root) == m)
+	be.True(t, f.Skip(root, filepath.Join(root, "photo.png")))
+	be.True(t, !f.Skip(root, filepath.Join(root, "photo.jpg")))
+	var none *ignore.Filter
+	be.True(t, !none.Skip(root, filepath.Join(root, "photo.png")))
+}
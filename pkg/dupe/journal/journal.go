@@ -0,0 +1,231 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+
+// Package journal keeps an append-only record of the files removed by the delete options,
+// so the removed duplicates can be recreated from their surviving copies.
+package journal
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bengarrett/dupers/internal/file"
+	"github.com/bengarrett/dupers/pkg/dupe/parse"
+	"github.com/gookit/color"
+)
+
+const (
+	// Name is the filename of the journal saved next to the database.
+	Name = "journal.jsonl"
+)
+
+var (
+	ErrNoEntry    = errors.New("the journal contains no entries")
+	ErrNoSurvivor = errors.New("the removed file has no surviving duplicate")
+	ErrDirectory  = errors.New("the removed directory and its unique content cannot be recreated")
+	ErrChanged    = errors.New("the surviving duplicate no longer matches the checksum")
+	ErrExist      = errors.New("the original path already exists")
+)
+
+func printf(w io.Writer, format string, a ...any) {
+	_, _ = fmt.Fprintf(w, format, a...)
+}
+
+// Entry is a single removal recorded in the journal.
+type Entry struct {
+	Path     string      `json:"path"`               // Path is the absolute path of the removed file or directory.
+	Checksum string      `json:"checksum,omitempty"` // Checksum is the SHA256 hash as a hexadecimal string.
+	Size     int64       `json:"size"`               // Size of the file in bytes.
+	Mode     fs.FileMode `json:"mode"`               // Mode is the file mode and permission bits.
+	ModTime  time.Time   `json:"modtime"`            // ModTime is the file modification time.
+	Survivor string      `json:"survivor,omitempty"` // Survivor is the path of the identical file that was kept.
+	Action   string      `json:"action"`             // Action taken on the path, such as removed.
+	Removed  time.Time   `json:"removed"`            // Removed is the time of the removal.
+}
+
+// Path returns the path of the journal that is kept next to the named database.
+// Any database other than dupers.db prefixes the journal with its name,
+// so the catalogs that share a directory keep their own journals.
+func Path(db string) string {
+	base := filepath.Base(db)
+	stem := strings.TrimSuffix(base, filepath.Ext(base))
+	if stem == "dupers" || stem == "" || stem == "." {
+		return filepath.Join(filepath.Dir(db), Name)
+	}
+	return filepath.Join(filepath.Dir(db), stem+"-"+Name)
+}
+
+// New returns an entry for the named file or directory, which must be called before the path is removed.
+// The checksum can be empty for a directory or for a file without a surviving duplicate.
+func New(name, survivor string, sum parse.Checksum) (Entry, error) {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return Entry{}, err
+	}
+	info, err := os.Lstat(abs)
+	if err != nil {
+		return Entry{}, err
+	}
+	e := Entry{
+		Path:     abs,
+		Size:     info.Size(),
+		Mode:     info.Mode(),
+		ModTime:  info.ModTime(),
+		Survivor: survivor,
+	}
+	if sum != (parse.Checksum{}) {
+		e.Checksum = hex.EncodeToString(sum[:])
+	}
+	return e, nil
+}
+
+// Append writes the entry to the end of the named journal.
+func Append(name string, e Entry) error {
+	if e.Removed.IsZero() {
+		e.Removed = time.Now()
+	}
+	if err := os.MkdirAll(filepath.Dir(name), file.PrivateDir); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_WRONLY, file.PrivateFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// Read returns the entries of the named journal.
+func Read(name string) ([]Entry, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entries := []Entry{}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		b := scanner.Bytes()
+		if len(strings.TrimSpace(string(b))) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(b, &e); err != nil {
+			return nil, fmt.Errorf("%w: %s line %d", err, name, line)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoEntry, name)
+	}
+	return entries, nil
+}
+
+// Undo recreates the files listed in the named journal by copying them back from their surviving duplicates.
+// The entries are undone in reverse order, and the parent directories of the files are created as needed.
+// Entries with an existing original path are skipped, so the journal can be undone more than once.
+// The removed directories and the files without a surviving duplicate are reported as unrecoverable,
+// as their content is lost.
+// It returns the number of recreated files.
+func Undo(w io.Writer, name string) (int, error) {
+	if w == nil {
+		w = io.Discard
+	}
+	entries, err := Read(name)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.Mode.IsDir() {
+			printf(w, "%s: %s\n", color.Warn.Sprint("unrecoverable"), fmt.Errorf("%w: %s", ErrDirectory, e.Path))
+			continue
+		}
+		if _, err := os.Lstat(e.Path); err == nil {
+			if !restored(e) {
+				printf(w, "%s: %s\n", color.Warn.Sprint("skipped"), fmt.Errorf("%w: %s", ErrExist, e.Path))
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(e.Path), file.PrivateDir); err != nil {
+			return count, err
+		}
+		if err := undo(e); err != nil {
+			label := "skipped"
+			if errors.Is(err, ErrNoSurvivor) {
+				label = "unrecoverable"
+			}
+			printf(w, "%s: %s\n", color.Warn.Sprint(label), err)
+			continue
+		}
+		count++
+		printf(w, "%s: %s\n", color.Secondary.Sprint("recreated"), e.Path)
+	}
+	return count, nil
+}
+
+// restored returns true when the existing path of the entry matches its checksum.
+func restored(e Entry) bool {
+	if e.Checksum == "" {
+		return false
+	}
+	sum, err := parse.Read(e.Path)
+	return err == nil && hex.EncodeToString(sum[:]) == e.Checksum
+}
+
+// undo recreates the file of the entry.
+func undo(e Entry) error {
+	if e.Survivor == "" || e.Checksum == "" {
+		return fmt.Errorf("%w: %s", ErrNoSurvivor, e.Path)
+	}
+	sum, err := parse.Read(e.Survivor)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrNoSurvivor, err)
+	}
+	if hex.EncodeToString(sum[:]) != e.Checksum {
+		return fmt.Errorf("%w: %s", ErrChanged, e.Survivor)
+	}
+	f, err := file.Temp(e.Path)
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if err := f.Chmod(e.Mode.Perm()); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := file.CopyTo(f, e.Survivor); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	_ = os.Chtimes(tmp, e.ModTime, e.ModTime)
+	if _, err := os.Lstat(e.Path); err == nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("%w: %s", ErrExist, e.Path)
+	}
+	if err := os.Rename(tmp, e.Path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
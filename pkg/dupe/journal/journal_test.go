@@ -0,0 +1,91 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+package journal_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bengarrett/dupers/internal/mock"
+	"github.com/bengarrett/dupers/pkg/dupe/journal"
+	"github.com/bengarrett/dupers/pkg/dupe/parse"
+	"github.com/gookit/color"
+	"github.com/nalgeon/be"
+)
+
+func TestPath(t *testing.T) {
+	db := filepath.Join("config", "dupers", "dupers.db")
+	be.Equal(t, journal.Path(db), filepath.Join("config", "dupers", journal.Name))
+	db = filepath.Join("config", "dupers", "photos.db")
+	be.Equal(t, journal.Path(db), filepath.Join("config", "dupers", "photos-"+journal.Name))
+}
+
+func TestUndo(t *testing.T) {
+	color.Enable = false
+	dir := t.TempDir()
+	name := filepath.Join(dir, journal.Name)
+	_, err := journal.Read(name)
+	be.Err(t, err)
+
+	keep := filepath.Join(dir, "keep.txt")
+	dup := filepath.Join(dir, "sub", "dupe.txt")
+	unique := filepath.Join(dir, "unique.txt")
+	err = os.MkdirAll(filepath.Dir(dup), mock.PrivateDir)
+	be.Err(t, err, nil)
+	for name, data := range map[string]string{keep: "undo me", dup: "undo me", unique: "unique"} {
+		err := os.WriteFile(name, []byte(data), mock.PrivateFile)
+		be.Err(t, err, nil)
+	}
+	sum, err := parse.Read(dup)
+	be.Err(t, err, nil)
+	for _, pair := range [][2]string{{dup, keep}, {unique, ""}} {
+		e, err := journal.New(pair[0], pair[1], sum)
+		be.Err(t, err, nil)
+		err = journal.Append(name, e)
+		be.Err(t, err, nil)
+	}
+	e, err := journal.New(filepath.Dir(dup), "", parse.Checksum{})
+	be.Err(t, err, nil)
+	be.Equal(t, e.Checksum, "")
+	err = os.RemoveAll(filepath.Dir(dup))
+	be.Err(t, err, nil)
+	err = journal.Append(name, e)
+	be.Err(t, err, nil)
+	err = os.Remove(unique)
+	be.Err(t, err, nil)
+
+	entries, err := journal.Read(name)
+	be.Err(t, err, nil)
+	be.Equal(t, len(entries), 3)
+	be.Equal(t, entries[0].Survivor, keep)
+
+	var w bytes.Buffer
+	n, err := journal.Undo(&w, name)
+	be.Err(t, err, nil)
+	// the removed directory is not counted, as only the duplicate within it is recreated
+	be.Equal(t, n, 1)
+	be.True(t, strings.Contains(w.String(), "recreated: "+dup))
+	be.True(t, strings.Contains(w.String(), "unrecoverable: "+journal.ErrNoSurvivor.Error()))
+	be.True(t, strings.Contains(w.String(), "unrecoverable: "+journal.ErrDirectory.Error()))
+	b, err := os.ReadFile(dup)
+	be.Err(t, err, nil)
+	be.Equal(t, string(b), "undo me")
+	// undo again, the recreated files are ignored
+	w.Reset()
+	n, err = journal.Undo(&w, name)
+	be.Err(t, err, nil)
+	be.Equal(t, n, 0)
+	be.True(t, !strings.Contains(w.String(), journal.ErrExist.Error()))
+	// a changed survivor is never copied
+	err = os.Remove(dup)
+	be.Err(t, err, nil)
+	err = os.WriteFile(keep, []byte("changed"), mock.PrivateFile)
+	be.Err(t, err, nil)
+	w.Reset()
+	n, err = journal.Undo(&w, name)
+	be.Err(t, err, nil)
+	be.Equal(t, n, 0)
+	be.True(t, strings.Contains(w.String(), journal.ErrChanged.Error()))
+}
@@ -0,0 +1,181 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+package dupe
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bengarrett/dupers/pkg/dupe/parse"
+	"github.com/gookit/color"
+)
+
+// Keep policies choose which file in a group of duplicates survives a removal.
+const (
+	KeepOldest   = "oldest"   // KeepOldest keeps the file with the oldest modification time.
+	KeepNewest   = "newest"   // KeepNewest keeps the file with the newest modification time.
+	KeepShortest = "shortest" // KeepShortest keeps the file with the shortest path.
+	KeepLongest  = "longest"  // KeepLongest keeps the file with the longest path.
+	KeepBucket   = "bucket:"  // KeepBucket is a prefix for the directory that contains the file to keep.
+	KeepGlob     = "glob:"    // KeepGlob is a prefix for the pattern that matches the file to keep.
+)
+
+var (
+	ErrKeep   = errors.New("unknown keep policy, use oldest, newest, shortest, longest, bucket:<dir> or glob:<pattern>")
+	ErrNoKeep = errors.New("no duplicate matches the keep policy")
+)
+
+// Keep is a parsed policy that chooses which file in a group of duplicates survives.
+type Keep struct {
+	Policy string // Policy is one of the keep constants.
+	Value  string // Value is the directory or pattern used by the bucket and glob policies.
+}
+
+// ParseKeep parses the named keep policy.
+func ParseKeep(name string) (Keep, error) {
+	s := strings.TrimSpace(name)
+	switch lower := strings.ToLower(s); {
+	case lower == KeepOldest, lower == KeepNewest, lower == KeepShortest, lower == KeepLongest:
+		return Keep{Policy: lower}, nil
+	case strings.HasPrefix(lower, KeepBucket):
+		dir := s[len(KeepBucket):]
+		if dir == "" {
+			return Keep{}, fmt.Errorf("%w: %q", ErrKeep, name)
+		}
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return Keep{}, err
+		}
+		return Keep{Policy: KeepBucket, Value: abs}, nil
+	case strings.HasPrefix(lower, KeepGlob):
+		pattern := s[len(KeepGlob):]
+		if _, err := filepath.Match(pattern, ""); err != nil || pattern == "" {
+			return Keep{}, fmt.Errorf("%w: %q", ErrKeep, name)
+		}
+		return Keep{Policy: KeepGlob, Value: pattern}, nil
+	default:
+		return Keep{}, fmt.Errorf("%w: %q", ErrKeep, name)
+	}
+}
+
+// Choose returns the path of the file to keep.
+// Ties are broken by the shortest path and then by name, so the choice is always the same.
+func (k Keep) Choose(paths ...string) (string, error) {
+	if len(paths) == 0 {
+		return "", ErrNoKeep
+	}
+	s := slices.Clone(paths)
+	slices.SortFunc(s, func(a, b string) int {
+		if len(a) != len(b) {
+			return len(a) - len(b)
+		}
+		return strings.Compare(a, b)
+	})
+	switch k.Policy {
+	case KeepShortest:
+		return s[0], nil
+	case KeepLongest:
+		return slices.MaxFunc(s, func(a, b string) int {
+			// MaxFunc returns the first maximal element
+			return len(a) - len(b)
+		}), nil
+	case KeepOldest, KeepNewest:
+		return k.modtime(s...)
+	case KeepBucket:
+		for _, path := range s {
+			if strings.HasPrefix(path, k.Value+string(filepath.Separator)) {
+				return path, nil
+			}
+		}
+		return "", fmt.Errorf("%w: %s%s", ErrNoKeep, KeepBucket, k.Value)
+	case KeepGlob:
+		for _, path := range s {
+			if ok, _ := filepath.Match(k.Value, path); ok {
+				return path, nil
+			}
+			if ok, _ := filepath.Match(k.Value, filepath.Base(path)); ok {
+				return path, nil
+			}
+		}
+		return "", fmt.Errorf("%w: %s%s", ErrNoKeep, KeepGlob, k.Value)
+	default:
+		return "", fmt.Errorf("%w: %q", ErrKeep, k.Policy)
+	}
+}
+
+// modtime returns the path with the oldest or newest modification time.
+func (k Keep) modtime(paths ...string) (string, error) {
+	keep := ""
+	var last os.FileInfo
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case last == nil,
+			k.Policy == KeepOldest && info.ModTime().Before(last.ModTime()),
+			k.Policy == KeepNewest && info.ModTime().After(last.ModTime()):
+			keep, last = path, info
+		}
+	}
+	return keep, nil
+}
+
+// delKeep removes every file in the group of duplicates except for the file chosen by the keep policy.
+// Files in a read-only bucket, that no longer exist or that no longer match the checksum are left untouched.
+func (c *Config) delKeep(w io.Writer, k Keep, sum parse.Checksum, paths ...string) {
+	group := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if slices.Contains(group, path) || c.Action(path) != "" {
+			continue
+		}
+		if c.Option(c.bucketOf(path)).ReadOnly {
+			c.Debugger("keep policy skipped a file in a read-only bucket: " + path)
+			continue
+		}
+		// re-verify the checksum as the database could be stale
+		if check, err := parse.Read(path); err != nil || check != sum {
+			c.Debugger("keep policy skipped an unmatched file: " + path)
+			continue
+		}
+		group = append(group, path)
+	}
+	if len(group) < 2 {
+		return
+	}
+	keep, err := k.Choose(group...)
+	if err != nil {
+		c.Debugger(err.Error())
+		return
+	}
+	printf(w, "%s: %s\n", color.Secondary.Sprint("kept"), keep)
+	for _, path := range group {
+		if path == keep {
+			continue
+		}
+		err := c.removeDupe(path, keep, sum)
+		printl(w, c.printRM(path, err))
+	}
+}
+
+// DelGroups removes every file in the groups of identical files found by Self,
+// except for the file chosen by the c.Keep policy.
+func (c *Config) DelGroups(groups ...Group) (string, error) {
+	k, err := ParseKeep(c.Keep)
+	if err != nil {
+		return "", err
+	}
+	w := new(strings.Builder)
+	for _, g := range groups {
+		c.delKeep(w, k, g.Sum, g.Paths...)
+	}
+	if w.Len() == 0 {
+		printl(w, "No duplicate files to remove.          ")
+	}
+	return w.String(), nil
+}
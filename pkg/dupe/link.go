@@ -0,0 +1,209 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+package dupe
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/bengarrett/dupers/internal/file"
+	"github.com/bengarrett/dupers/internal/printer"
+	"github.com/bengarrett/dupers/pkg/database/record"
+	"github.com/bengarrett/dupers/pkg/dupe/parse"
+	"github.com/dustin/go-humanize"
+	"github.com/gookit/color"
+)
+
+// Link types used to replace the duplicate files in the source.
+const (
+	LinkHard = "hard" // LinkHard replaces a duplicate with a hard link to the kept copy.
+	LinkSym  = "sym"  // LinkSym replaces a duplicate with a symbolic link to the kept copy.
+)
+
+var (
+	ErrLink        = errors.New("unknown link type, use either hard or sym")
+	ErrLinkDevice  = errors.New("hard links need both files to be on the same file system")
+	ErrLinkChanged = errors.New("the file content changed and was not linked")
+)
+
+// CheckLink returns an error if the named link type is unknown.
+// An empty link type is valid and means the duplicates are not linked.
+func CheckLink(name string) error {
+	switch strings.ToLower(name) {
+	case "", LinkHard, LinkSym:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrLink, name)
+	}
+}
+
+// Link replaces the duplicate files in the source with hard or symbolic links to a matching file.
+// The kept copy is chosen by the c.Keep policy, or is otherwise the first match.
+// Every match is checked again before it is used, as the database could be stale.
+func (c *Config) Link(kind string) (string, error) {
+	kind = strings.ToLower(kind)
+	if err := CheckLink(kind); err != nil {
+		return "", err
+	}
+	var k Keep
+	if c.Keep != "" {
+		var err error
+		if k, err = ParseKeep(c.Keep); err != nil {
+			return "", err
+		}
+	}
+	c.Debugger("replace duplicate files with " + kind + " links.")
+	w := new(bytes.Buffer)
+	files, err := c.sourceFiles()
+	if err != nil {
+		return "", err
+	}
+	count, saved := 0, int64(0)
+	for _, path := range files {
+		info, err := os.Lstat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		sum, err := parse.Read(path)
+		if err != nil {
+			return "", err
+		}
+		target := c.target(k, path, sum)
+		if target == "" {
+			continue
+		}
+		err = c.link(kind, path, target, sum)
+		if err == nil && c.DryRun {
+			printl(w, fmt.Sprintf("%s: %s %s %s", color.Secondary.Sprint("would link"),
+				path, color.Success.Sprint("⇒"), target))
+		} else {
+			printl(w, PrintLink(path, target, err))
+		}
+		if err != nil {
+			continue
+		}
+		count++
+		saved += info.Size()
+	}
+	if count == 0 {
+		printl(w, "No duplicate files to link.          ")
+		return w.String(), nil
+	}
+	verb := "Linked"
+	if c.DryRun {
+		verb = "Would link"
+	}
+	printf(w, "%s %d files, reclaiming %s\n", verb, count, humanize.Bytes(safesize(saved)))
+	return w.String(), nil
+}
+
+// target returns the matching file that the named path links to, or an empty string if there is none
+// or if the path is already a hard link to a match.
+// Matches that are within the source, that were already replaced, or no longer share the checksum are ignored.
+func (c *Config) target(k Keep, path string, sum parse.Checksum) string {
+	pi, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	candidates := []string{}
+	for _, match := range c.matches(path, sum) {
+		if slices.Contains(c.Sources, match) || c.Action(match) != "" {
+			continue
+		}
+		info, err := os.Stat(match)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if os.SameFile(pi, info) {
+			c.Debugger("already linked: " + path)
+			return ""
+		}
+		if check, err := parse.Read(match); err != nil || check != sum {
+			c.Debugger("link skipped an unmatched file: " + match)
+			continue
+		}
+		candidates = append(candidates, match)
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	if k.Policy == "" {
+		return candidates[0]
+	}
+	target, err := k.Choose(candidates...)
+	if err != nil {
+		c.Debugger(err.Error())
+		return ""
+	}
+	return target
+}
+
+// link atomically replaces the named path with a hard or symbolic link to the target.
+// The link is created using a temporary name in the same directory and is then renamed over the path.
+// A dry run only checks that the link is possible.
+func (c *Config) link(kind, path, target string, sum parse.Checksum) error {
+	pi, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	ti, err := os.Stat(target)
+	if err != nil {
+		return err
+	}
+	if os.SameFile(pi, ti) {
+		return fmt.Errorf("%w: %s", os.ErrExist, path)
+	}
+	if kind == LinkHard {
+		p, t := record.New(sum, pi), record.New(sum, ti)
+		if p.Inode != 0 && t.Inode != 0 && p.Dev != t.Dev {
+			return fmt.Errorf("%w: %s", ErrLinkDevice, target)
+		}
+	}
+	if c.DryRun {
+		c.act(path, ActDryRun)
+		return nil
+	}
+	// reserve a unique name, then remove the empty file as a link is never created over an existing file
+	f, err := file.Temp(path)
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_ = f.Close()
+	if err := os.Remove(tmp); err != nil {
+		return err
+	}
+	switch kind {
+	case LinkHard:
+		err = os.Link(target, tmp)
+	case LinkSym:
+		err = os.Symlink(target, tmp)
+	}
+	if err != nil {
+		return err
+	}
+	// verify the content immediately before the swap
+	if check, err := parse.Read(path); err != nil || check != sum {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("%w: %s", ErrLinkChanged, path)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	c.act(path, ActLinked)
+	return nil
+}
+
+// PrintLink prints "linked:" with the path and its target, or prints any error to stderr.
+func PrintLink(path, target string, err error) string {
+	if err != nil {
+		e := fmt.Errorf("could not link: %w", err)
+		printer.StderrCR(e)
+		return ""
+	}
+	return fmt.Sprintf("%s: %s %s %s", color.Secondary.Sprint("linked"), path, color.Success.Sprint("⇒"), target)
+}
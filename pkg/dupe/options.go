@@ -0,0 +1,40 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+package dupe
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+)
+
+var ErrReadOnly = errors.New("bucket is read-only")
+
+// Options are the settings of a bucket, as set in the configuration file.
+type Options struct {
+	ReadOnly bool // ReadOnly buckets are never walked, cleaned or changed, only their stored checksums are used.
+	Archives bool // Archives also hashes the content of the archive files within the bucket.
+}
+
+// Option returns the settings of the named bucket.
+func (c *Config) Option(bucket string) Options {
+	if c.Options == nil || bucket == "" {
+		return Options{}
+	}
+	return c.Options[filepath.Clean(bucket)]
+}
+
+// ReadOnly returns an error if the named bucket is read-only.
+func (c *Config) ReadOnly(bucket string) error {
+	if c.Option(bucket).ReadOnly {
+		return fmt.Errorf("%w: %s", ErrReadOnly, bucket)
+	}
+	return nil
+}
+
+// Writable returns the named buckets that are not read-only.
+func (c *Config) Writable(buckets ...string) []string {
+	return slices.DeleteFunc(slices.Clone(buckets), func(name string) bool {
+		return c.Option(name).ReadOnly
+	})
+}
@@ -26,20 +26,27 @@ import (
 )
 
 type (
-	Bucket    string              // Bucket is a database table named as an absolute directory path.
-	Checksum  [32]byte            // Checksum is a SHA-1 hash file value.
-	Checksums map[Checksum]string // Checksums is a collection of SHA-1 hash file values.
+	Bucket    string                           // Bucket is a database table named as an absolute directory path.
+	Checksum  [32]byte                         // Checksum is a SHA-1 hash file value.
+	Checksums map[Checksum]string              // Checksums is a collection of SHA-1 hash file values.
+	Paths     map[Checksum][]database.Filepath // Paths is a collection of files that share a hash value.
+	Sizes     map[database.Filepath]int64      // Sizes is a collection of file sizes in bytes.
 )
 
 const (
 	oneKb = 1024
 	oneMb = oneKb * oneKb
+
+	// PartSize is the number of bytes read from both the start and the end of a file by ReadPartial.
+	PartSize = 64 * oneKb
 )
 
 type Scanner struct {
 	Sources []string  // Sources to compare, either directories or files.
 	Buckets []Bucket  // Buckets to lookup.
 	Compare Checksums // Compare hashes fetched from the database or file system.
+	Index   Paths     // Index of every path that shares a hash in Compare.
+	Sizes   Sizes     // Sizes of the paths in Index.
 	Files   int       // Files counter of the totals scanned and processed.
 	timer   time.Time
 }
@@ -103,17 +110,58 @@ func (p *Scanner) SetCompares(db *bolt.DB, name Bucket) (int, error) {
 	if db == nil {
 		return 0, bberr.ErrDatabaseNotOpen
 	}
-	list, err := database.List(db, string(name))
+	list, err := database.ListRecords(db, string(name))
 	if err != nil {
 		return 0, err
 	}
+	for path, r := range list {
+		p.Add(r.Sum, string(path))
+		if r.Stat() {
+			p.SetSize(string(path), r.Size)
+		}
+	}
+	return len(list), nil
+}
+
+// Add the checksum and path to both the Compare and Index scanners.
+// A path that is already indexed under the checksum is ignored.
+func (p *Scanner) Add(sum Checksum, path string) {
 	if p.Compare == nil {
 		p.Compare = make(Checksums)
 	}
-	for path, sum := range list {
-		p.Compare[sum] = string(path)
+	if p.Index == nil {
+		p.Index = make(Paths)
 	}
-	return len(list), nil
+	p.Compare[sum] = path
+	name := database.Filepath(path)
+	if slices.Contains(p.Index[sum], name) {
+		return
+	}
+	p.Index[sum] = append(p.Index[sum], name)
+}
+
+// SetSize sets the file size in bytes of the indexed path.
+func (p *Scanner) SetSize(path string, size int64) {
+	if p.Sizes == nil {
+		p.Sizes = make(Sizes)
+	}
+	p.Sizes[database.Filepath(path)] = size
+}
+
+// Lookup returns every path that shares the checksum.
+// If the checksum is not indexed, the single path held by Compare is returned.
+func (p *Scanner) Lookup(sum Checksum) []string {
+	if paths, ok := p.Index[sum]; ok {
+		s := make([]string, 0, len(paths))
+		for _, path := range paths {
+			s = append(s, string(path))
+		}
+		return s
+	}
+	if path := p.Compare[sum]; path != "" {
+		return []string{path}
+	}
+	return nil
 }
 
 // SetTimer starts a process timer.
@@ -170,6 +218,41 @@ func Contains(find string, s ...string) bool {
 
 // Print the results of the database comparisons.
 func Print(quiet, exact bool, term string, m *database.Matches) string {
+	return printMatches(quiet, m, func(path database.Filepath) string {
+		return Marker(path, term, exact)
+	})
+}
+
+// PrintPattern prints the results of the database pattern comparisons.
+func PrintPattern(quiet bool, p *database.Pattern, m *database.Matches) string {
+	return printMatches(quiet, m, func(path database.Filepath) string {
+		return MarkPattern(path, p)
+	})
+}
+
+// PrintRanked prints the results of the fuzzy comparisons in ranked order, from the closest match.
+// Unlike the other results, they are not grouped by bucket.
+func PrintRanked(quiet, exact bool, term string, r database.Ranks) string {
+	if len(r) == 0 {
+		return ""
+	}
+	w := new(bytes.Buffer)
+	if !quiet {
+		fmt.Fprintf(w, "%s: %s\n", color.Info.Sprint("Closest matches to"), term)
+	}
+	for i, rank := range r {
+		if quiet {
+			fmt.Fprintf(w, "%s\n", rank.Path)
+			continue
+		}
+		fmt.Fprintf(w, "  %s%s\t%s\n", color.Primary.Sprint(i+1),
+			color.Secondary.Sprint("."), Marker(rank.Path, term, exact))
+	}
+	return w.String()
+}
+
+// printMatches prints the matches sorted by bucket, using the marker func to highlight each path.
+func printMatches(quiet bool, m *database.Matches, marker func(database.Filepath) string) string {
 	if m == nil || len(*m) == 0 {
 		return ""
 	}
@@ -200,7 +283,7 @@ func Print(quiet, exact bool, term string, m *database.Matches) string {
 				fmt.Fprintf(w, "%s\n", path)
 				continue
 			}
-			mark := Marker(path, term, exact)
+			mark := marker(path)
 			if cnt == 1 {
 				fmt.Fprintf(w, "%s%s\n", color.Success.Sprint(printer.MatchPrefix),
 					mark)
@@ -230,6 +313,39 @@ func Read(name string) (Checksum, error) {
 	return c, nil
 }
 
+// ReadPartial the named file and return a SHA256 checksum of the first and last PartSize bytes.
+// The partial checksum can quickly tell apart files of the same size,
+// but a match does not mean the files are identical.
+func ReadPartial(name string) (Checksum, error) {
+	name = filepath.Clean(name)
+	src, err := os.Open(name)
+	if err != nil {
+		return Checksum{}, err
+	}
+	defer func() { _ = src.Close() }()
+	stat, err := src.Stat()
+	if err != nil {
+		return Checksum{}, err
+	}
+	dst := sha256.New()
+	size := stat.Size()
+	if size <= PartSize*2 {
+		if _, err := io.Copy(dst, src); err != nil {
+			return Checksum{}, err
+		}
+	} else {
+		if _, err := io.Copy(dst, io.NewSectionReader(src, 0, PartSize)); err != nil {
+			return Checksum{}, err
+		}
+		if _, err := io.Copy(dst, io.NewSectionReader(src, size-PartSize, PartSize)); err != nil {
+			return Checksum{}, err
+		}
+	}
+	var c Checksum
+	copy(c[:], dst.Sum(nil))
+	return c, nil
+}
+
 // Marker uses ANSI color to highlight the term contained in the filepath.
 func Marker(file database.Filepath, term string, exact bool) string {
 	s := string(file)
@@ -248,8 +364,36 @@ func markExact(s, substr string) string {
 }
 
 func markInsensitive(s, substr string) string {
-	re := regexp.MustCompile(fmt.Sprintf("(?i)(%s)", substr))
-	return re.ReplaceAllString(s, color.Info.Sprint("$1"))
+	if substr == "" {
+		return s
+	}
+	re := regexp.MustCompile("(?i)" + regexp.QuoteMeta(substr))
+	return mark(s, re.FindAllStringIndex(s, -1))
+}
+
+// MarkPattern uses ANSI color to highlight the text in the filepath that is matched by the pattern.
+func MarkPattern(file database.Filepath, p *database.Pattern) string {
+	s := string(file)
+	if !color.Enable {
+		return s
+	}
+	return mark(s, p.Index(s))
+}
+
+// mark highlights the start and end positions of the spans within the string.
+func mark(s string, spans [][]int) string {
+	var b strings.Builder
+	last := 0
+	for _, span := range spans {
+		if span[0] < last || span[1] <= span[0] {
+			continue
+		}
+		b.WriteString(s[last:span[0]])
+		b.WriteString(color.Info.Sprint(s[span[0]:span[1]]))
+		last = span[1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
 }
 
 func matchBuckets(m *database.Matches) (string, []string) {
@@ -62,6 +62,21 @@ func TestParser_SetCompares(t *testing.T) {
 	be.Equal(t, bucket2Items, i)
 }
 
+func TestParser_Add(t *testing.T) {
+	s := parse.Scanner{}
+	var sum parse.Checksum
+	be.Equal(t, len(s.Lookup(sum)), 0)
+	const a, b = "/some/file", "/another/file"
+	s.Add(sum, a)
+	s.Add(sum, b)
+	s.Add(sum, a)
+	be.Equal(t, s.Lookup(sum), []string{a, b})
+	be.Equal(t, len(s.Compare), 1)
+	// fallback to compare when the checksum is not indexed
+	s = parse.Scanner{Compare: parse.Checksums{sum: a}}
+	be.Equal(t, s.Lookup(sum), []string{a})
+}
+
 func TestContains(t *testing.T) {
 	randm := []string{"weight", "teacher", "budge", "enthusiasm", "familiar"}
 	b := parse.Contains("", "")
@@ -119,6 +134,37 @@ func TestRead(t *testing.T) {
 	be.Equal(t, true, ok)
 }
 
+func TestReadPartial(t *testing.T) {
+	_, err := parse.ReadPartial("")
+	be.Err(t, err)
+	// small files are read in full
+	item1 := mock.Item(t, 1)
+	sum, err := parse.ReadPartial(item1)
+	be.Err(t, err, nil)
+	be.True(t, mock.Sum(t, 1, sum))
+	// large files only compare the start and the end
+	tmp := t.TempDir()
+	b := make([]byte, parse.PartSize*3)
+	name1 := filepath.Join(tmp, "partial1")
+	err = os.WriteFile(name1, b, mock.PrivateFile)
+	be.Err(t, err, nil)
+	b[parse.PartSize+1] = 'x'
+	name2 := filepath.Join(tmp, "partial2")
+	err = os.WriteFile(name2, b, mock.PrivateFile)
+	be.Err(t, err, nil)
+	sum1, err := parse.ReadPartial(name1)
+	be.Err(t, err, nil)
+	sum2, err := parse.ReadPartial(name2)
+	be.Err(t, err, nil)
+	be.Equal(t, sum1, sum2)
+	b[len(b)-1] = 'x'
+	err = os.WriteFile(name2, b, mock.PrivateFile)
+	be.Err(t, err, nil)
+	sum2, err = parse.ReadPartial(name2)
+	be.Err(t, err, nil)
+	be.True(t, sum1 != sum2)
+}
+
 func Test_SetBucket(t *testing.T) {
 	s := parse.Scanner{}
 	err := s.SetBuckets("")
@@ -170,6 +216,27 @@ func TestMarker(t *testing.T) {
 	be.Equal(t, item1, s)
 	s = parse.Marker(file, term, true)
 	be.Equal(t, item1, s)
+	// terms containing regular expression syntax are matched as plain text
+	color.Enable = true
+	defer func() { color.Enable = false }()
+	s = parse.Marker("/tmp/c++ (1).txt", "+ (", false)
+	be.True(t, strings.Contains(s, "c+"))
+	be.True(t, strings.Contains(s, "1).txt"))
+}
+
+func TestMarkPattern(t *testing.T) {
+	color.Enable = false
+	const name = "/home/user/photo01.jpg"
+	p, err := database.Glob("*.jpg", false, false)
+	be.Err(t, err, nil)
+	s := parse.MarkPattern(name, p)
+	be.Equal(t, name, s)
+	color.Enable = true
+	defer func() { color.Enable = false }()
+	s = parse.MarkPattern(name, p)
+	be.True(t, s != name)
+	be.True(t, strings.HasPrefix(s, "/home/user/"))
+	be.True(t, strings.Contains(s, "photo01.jpg"))
 }
 
 func TestPrint(t *testing.T) {
@@ -192,6 +259,20 @@ func TestPrint(t *testing.T) {
 	// exact and term are untested as they only effect ANSI color output.
 }
 
+func TestPrintRanked(t *testing.T) {
+	s := parse.PrintRanked(false, false, "", nil)
+	be.Equal(t, "", s)
+	r := database.Ranks{
+		{Path: "/home/user/receipt.pdf", Score: 1},
+		{Path: "/home/user/recipe.txt", Score: 1},
+	}
+	s = parse.PrintRanked(true, false, "recipt", r)
+	be.Equal(t, s, "/home/user/receipt.pdf\n/home/user/recipe.txt\n")
+	s = parse.PrintRanked(false, false, "recipt", r)
+	be.True(t, strings.Contains(s, "recipt"))
+	be.True(t, strings.Index(s, "receipt.pdf") < strings.Index(s, "recipe.txt"))
+}
+
 // BenchmarkChecksum benchmarks the Checksum function performance.
 func BenchmarkChecksum(b *testing.B) {
 	// Create test data of different sizes
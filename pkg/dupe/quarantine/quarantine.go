@@ -0,0 +1,274 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+
+// Package quarantine moves files into a dated directory instead of deleting them,
+// keeping a manifest of every move so the files can be restored.
+package quarantine
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bengarrett/dupers/internal/file"
+	"github.com/gookit/color"
+)
+
+const (
+	// Manifest is the filename of the manifest saved in the dated directory.
+	Manifest = "manifest.jsonl"
+	// Layout is the time format used to name the dated directory.
+	Layout = "2006-01-02_150405"
+)
+
+var (
+	ErrNoDir   = errors.New("the quarantine directory cannot be empty")
+	ErrInside  = errors.New("the path is already within the quarantine directory")
+	ErrExist   = errors.New("the original path already exists")
+	ErrNoEntry = errors.New("the manifest contains no entries")
+)
+
+func printf(w io.Writer, format string, a ...any) {
+	_, _ = fmt.Fprintf(w, format, a...)
+}
+
+// Entry is a single move recorded in the manifest.
+type Entry struct {
+	Path    string      `json:"path"`          // Path is the original absolute path.
+	File    string      `json:"file"`          // File is the path within the dated directory.
+	Dir     bool        `json:"dir,omitempty"` // Dir is true for a directory and its content.
+	Size    int64       `json:"size"`          // Size of the file in bytes.
+	Mode    fs.FileMode `json:"mode"`          // Mode is the file mode and permission bits.
+	ModTime time.Time   `json:"modtime"`       // ModTime is the file modification time.
+	Moved   time.Time   `json:"moved"`         // Moved is the time the file was quarantined.
+}
+
+// Store is a dated quarantine directory.
+type Store struct {
+	Root  string // Root is the dated directory containing the moved files and the manifest.
+	Count int    // Count of the moved files and directories.
+}
+
+// New returns a store for a dated directory within dir.
+// Nothing is created until the first file is moved.
+func New(dir string, now time.Time) (*Store, error) {
+	if strings.TrimSpace(dir) == "" {
+		return nil, ErrNoDir
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{Root: filepath.Join(abs, now.Format(Layout))}, nil
+}
+
+// Manifest returns the path of the manifest file.
+func (s *Store) Manifest() string {
+	return filepath.Join(s.Root, Manifest)
+}
+
+// Mirror returns the path within the dated directory for the named absolute path.
+// The original directory structure is kept, while a Windows drive letter becomes a directory.
+func Mirror(name string) string {
+	vol := filepath.VolumeName(name)
+	rel := strings.TrimLeft(name[len(vol):], `/\`)
+	vol = strings.Trim(strings.ReplaceAll(vol, ":", ""), `/\`)
+	if vol == "" {
+		return filepath.Clean(rel)
+	}
+	return filepath.Join(vol, rel)
+}
+
+// Move the named file or directory into the store and record the move in the manifest.
+func (s *Store) Move(name string) error {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return err
+	}
+	if abs == s.Root || strings.HasPrefix(abs, s.Root+string(filepath.Separator)) {
+		return fmt.Errorf("%w: %s", ErrInside, abs)
+	}
+	info, err := os.Lstat(abs)
+	if err != nil {
+		return err
+	}
+	mirror := Mirror(abs)
+	dst := filepath.Join(s.Root, mirror)
+	if err := os.MkdirAll(filepath.Dir(dst), file.PrivateDir); err != nil {
+		return err
+	}
+	if err := s.move(abs, dst, info); err != nil {
+		return err
+	}
+	s.Count++
+	return s.write(Entry{
+		Path:    abs,
+		File:    mirror,
+		Dir:     info.IsDir(),
+		Size:    info.Size(),
+		Mode:    info.Mode(),
+		ModTime: info.ModTime(),
+		Moved:   time.Now(),
+	})
+}
+
+// move the src path to dst, a file is never moved over an existing quarantined file.
+// An empty directory whose mirror already exists is removed, as the mirror holds its quarantined content.
+func (s *Store) move(src, dst string, info fs.FileInfo) error {
+	st, err := os.Lstat(dst)
+	if err != nil {
+		return Rename(src, dst)
+	}
+	if info.IsDir() && st.IsDir() && os.Remove(src) == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", fs.ErrExist, dst)
+}
+
+// write appends the entry to the manifest.
+func (s *Store) write(e Entry) error {
+	f, err := os.OpenFile(s.Manifest(), os.O_CREATE|os.O_APPEND|os.O_WRONLY, file.PrivateFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// Read returns the entries of the named manifest.
+func Read(manifest string) ([]Entry, error) {
+	f, err := os.Open(manifest)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entries := []Entry{}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		b := scanner.Bytes()
+		if len(strings.TrimSpace(string(b))) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(b, &e); err != nil {
+			return nil, fmt.Errorf("%w: %s line %d", err, manifest, line)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoEntry, manifest)
+	}
+	return entries, nil
+}
+
+// Restore moves the files listed in the named manifest back to their original paths.
+// The entries are restored in reverse order, so directories are put back before their content.
+// Entries that were already restored are ignored, while those with an existing original path are skipped.
+// It returns the number of restored entries.
+func Restore(w io.Writer, manifest string) (int, error) {
+	if w == nil {
+		w = io.Discard
+	}
+	entries, err := Read(manifest)
+	if err != nil {
+		return 0, err
+	}
+	root := filepath.Dir(manifest)
+	count := 0
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		src := filepath.Join(root, filepath.Clean(e.File))
+		if _, err := os.Lstat(src); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if _, err := os.Lstat(e.Path); err == nil {
+			if e.Dir && os.Remove(src) == nil {
+				// the empty directory was recreated by an earlier entry
+				continue
+			}
+			printf(w, "%s: %s\n", color.Warn.Sprint("skipped"), fmt.Errorf("%w: %s", ErrExist, e.Path))
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(e.Path), file.PrivateDir); err != nil {
+			return count, err
+		}
+		if err := Rename(src, e.Path); err != nil {
+			return count, err
+		}
+		count++
+		printf(w, "%s: %s\n", color.Secondary.Sprint("restored"), e.Path)
+	}
+	return count, nil
+}
+
+// Rename moves the src path to dst.
+// When a rename is not possible, such as between file systems, the path is copied and then removed.
+func Rename(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil {
+		return nil
+	}
+	var le *os.LinkError
+	if !errors.As(err, &le) {
+		return err
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		return fmt.Errorf("%w: %s", fs.ErrExist, dst)
+	}
+	if err := copyAll(src, dst); err != nil {
+		_ = os.RemoveAll(dst)
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// copyAll copies the src file or directory tree to dst, keeping the permissions and modification times.
+func copyAll(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			if err := os.MkdirAll(target, info.Mode().Perm()|0o700); err != nil {
+				return err
+			}
+		case info.Mode()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		default:
+			if err := file.Copy(path, target, info.Mode().Perm()); err != nil {
+				return err
+			}
+		}
+		return os.Chtimes(target, info.ModTime(), info.ModTime())
+	})
+}
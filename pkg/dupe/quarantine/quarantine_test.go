@@ -0,0 +1,101 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+package quarantine_test
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bengarrett/dupers/internal/mock"
+	"github.com/bengarrett/dupers/pkg/dupe/quarantine"
+	"github.com/gookit/color"
+	"github.com/nalgeon/be"
+)
+
+func TestMirror(t *testing.T) {
+	name := filepath.Join(string(filepath.Separator), "home", "user", "file.txt")
+	be.Equal(t, quarantine.Mirror(name), filepath.Join("home", "user", "file.txt"))
+}
+
+func TestStore(t *testing.T) {
+	color.Enable = false
+	_, err := quarantine.New(" ", time.Now())
+	be.Err(t, err, quarantine.ErrNoDir)
+
+	src, dir := t.TempDir(), t.TempDir()
+	file := filepath.Join(src, "file.txt")
+	tree := filepath.Join(src, "tree")
+	empty := filepath.Join(src, "parent", "empty")
+	err = os.WriteFile(file, []byte("quarantine"), mock.PrivateFile)
+	be.Err(t, err, nil)
+	err = os.MkdirAll(filepath.Join(tree, "sub"), mock.PrivateDir)
+	be.Err(t, err, nil)
+	err = os.WriteFile(filepath.Join(tree, "sub", "nested.txt"), []byte("nested"), mock.PrivateFile)
+	be.Err(t, err, nil)
+	err = os.MkdirAll(empty, mock.PrivateDir)
+	be.Err(t, err, nil)
+
+	s, err := quarantine.New(dir, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+	be.Err(t, err, nil)
+	be.Equal(t, filepath.Base(s.Root), "2020-01-02_030405")
+	// empty directories are removed child first, so the parent is mirrored twice
+	for _, name := range []string{file, tree, empty, filepath.Dir(empty)} {
+		err = s.Move(name)
+		be.Err(t, err, nil)
+		_, err = os.Stat(name)
+		be.Err(t, err, fs.ErrNotExist)
+	}
+	be.Equal(t, s.Count, 4)
+	err = s.Move(s.Manifest())
+	be.Err(t, err, quarantine.ErrInside)
+	moved := filepath.Join(s.Root, quarantine.Mirror(file))
+	b, err := os.ReadFile(moved)
+	be.Err(t, err, nil)
+	be.Equal(t, string(b), "quarantine")
+
+	entries, err := quarantine.Read(s.Manifest())
+	be.Err(t, err, nil)
+	be.Equal(t, len(entries), 4)
+	be.Equal(t, entries[0].Path, file)
+	be.Equal(t, entries[0].Size, int64(len("quarantine")))
+	be.True(t, entries[1].Dir)
+
+	// an existing original path is never overwritten
+	err = os.WriteFile(file, []byte("new"), mock.PrivateFile)
+	be.Err(t, err, nil)
+	var w bytes.Buffer
+	n, err := quarantine.Restore(&w, s.Manifest())
+	be.Err(t, err, nil)
+	be.Equal(t, n, 2)
+	be.True(t, strings.Contains(w.String(), "skipped"))
+	for _, name := range []string{filepath.Join(tree, "sub", "nested.txt"), empty} {
+		_, err = os.Stat(name)
+		be.Err(t, err, nil)
+	}
+	b, err = os.ReadFile(file)
+	be.Err(t, err, nil)
+	be.Equal(t, string(b), "new")
+	// restore again only skips the existing file
+	err = os.Remove(file)
+	be.Err(t, err, nil)
+	n, err = quarantine.Restore(nil, s.Manifest())
+	be.Err(t, err, nil)
+	be.Equal(t, n, 1)
+	b, err = os.ReadFile(file)
+	be.Err(t, err, nil)
+	be.Equal(t, string(b), "quarantine")
+}
+
+func TestRead(t *testing.T) {
+	_, err := quarantine.Read(filepath.Join(t.TempDir(), quarantine.Manifest))
+	be.Err(t, err, fs.ErrNotExist)
+	name := filepath.Join(t.TempDir(), quarantine.Manifest)
+	err = os.WriteFile(name, []byte("\n"), mock.PrivateFile)
+	be.Err(t, err, nil)
+	_, err = quarantine.Read(name)
+	be.Err(t, err, quarantine.ErrNoEntry)
+}
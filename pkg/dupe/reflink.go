@@ -0,0 +1,149 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+package dupe
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/bengarrett/dupers/internal/file"
+	"github.com/bengarrett/dupers/internal/printer"
+	"github.com/bengarrett/dupers/pkg/dupe/parse"
+	"github.com/dustin/go-humanize"
+	"github.com/gookit/color"
+)
+
+var (
+	ErrReflink        = errors.New("reflinks are not supported by this file system, use btrfs or xfs")
+	ErrReflinkDiffers = errors.New("the file content differs and was not shared")
+)
+
+// Reflink deduplicates the duplicate files in the source by sharing the data extents of a matching file,
+// using copy-on-write clones that are supported by file systems such as btrfs and XFS.
+// The duplicates stay as independent files that can later be changed without affecting the match.
+//
+// The kernel first tries to share the extents of the existing files, comparing their content while doing so.
+// When this is not possible, the match is cloned to a temporary file that is then renamed over the duplicate.
+// The kept copy is chosen by the c.Keep policy, or is otherwise the first match.
+func (c *Config) Reflink() (string, error) {
+	var k Keep
+	if c.Keep != "" {
+		var err error
+		if k, err = ParseKeep(c.Keep); err != nil {
+			return "", err
+		}
+	}
+	c.Debugger("share the extents of duplicate files using reflinks.")
+	w := new(bytes.Buffer)
+	files, err := c.sourceFiles()
+	if err != nil {
+		return "", err
+	}
+	count, shared := 0, int64(0)
+	for _, path := range files {
+		info, err := os.Lstat(path)
+		if err != nil || !info.Mode().IsRegular() || info.Size() == 0 {
+			continue
+		}
+		sum, err := parse.Read(path)
+		if err != nil {
+			return "", err
+		}
+		target := c.target(k, path, sum)
+		if target == "" {
+			continue
+		}
+		n, err := c.reflink(path, target, sum)
+		if err == nil && c.DryRun {
+			printl(w, fmt.Sprintf("%s: %s %s %s", color.Secondary.Sprint("would reflink"),
+				path, color.Success.Sprint("⇔"), target))
+		} else {
+			printl(w, PrintReflink(path, target, err))
+		}
+		if errors.Is(err, ErrReflink) {
+			// every other file in the source is very likely on the same file system
+			return w.String(), err
+		}
+		if err != nil {
+			continue
+		}
+		count++
+		shared += n
+	}
+	if count == 0 {
+		printl(w, "No duplicate files to reflink.          ")
+		return w.String(), nil
+	}
+	verb := "Reflinked"
+	if c.DryRun {
+		verb = "Would reflink"
+	}
+	printf(w, "%s %d files, sharing %s\n", verb, count, humanize.Bytes(safesize(shared)))
+	return w.String(), nil
+}
+
+// reflink shares the extents of the target with the named path and returns the number of bytes shared.
+// A dry run returns the size of the path without sharing anything.
+func (c *Config) reflink(path, target string, sum parse.Checksum) (int64, error) {
+	if c.DryRun {
+		info, err := os.Stat(path)
+		if err != nil {
+			return 0, err
+		}
+		c.act(path, ActDryRun)
+		return info.Size(), nil
+	}
+	n, err := dedupe(target, path)
+	if err == nil {
+		c.act(path, ActReflinked)
+		return n, nil
+	}
+	if !errors.Is(err, ErrReflink) {
+		return 0, err
+	}
+	c.Debugger("dedupe range is not supported, try a clone: " + err.Error())
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	f, err := file.Temp(path)
+	if err != nil {
+		return 0, err
+	}
+	tmp := f.Name()
+	err = f.Chmod(info.Mode().Perm())
+	if err == nil {
+		err = clone(target, f)
+	}
+	if err := errors.Join(err, f.Close()); err != nil {
+		_ = os.Remove(tmp)
+		return 0, err
+	}
+	// verify the content immediately before the swap
+	if check, err := parse.Read(path); err != nil || check != sum {
+		_ = os.Remove(tmp)
+		return 0, fmt.Errorf("%w: %s", ErrLinkChanged, path)
+	}
+	if check, err := parse.Read(tmp); err != nil || check != sum {
+		_ = os.Remove(tmp)
+		return 0, fmt.Errorf("%w: %s", ErrReflinkDiffers, target)
+	}
+	_ = os.Chtimes(tmp, info.ModTime(), info.ModTime())
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return 0, err
+	}
+	c.act(path, ActReflinked)
+	return info.Size(), nil
+}
+
+// PrintReflink prints "reflinked:" with the path and its shared file, or prints any error to stderr.
+func PrintReflink(path, target string, err error) string {
+	if err != nil {
+		e := fmt.Errorf("could not reflink: %w", err)
+		printer.StderrCR(e)
+		return ""
+	}
+	return fmt.Sprintf("%s: %s %s %s", color.Secondary.Sprint("reflinked"), path, color.Success.Sprint("⇔"), target)
+}
@@ -0,0 +1,108 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+
+//go:build linux
+
+package dupe
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// dedupeLen is the maximum number of bytes shared by a single dedupe request,
+// as some file systems limit the length of each request.
+const dedupeLen = 16 * 1024 * 1024
+
+// unsupported returns true when the error means the file system cannot share extents.
+func unsupported(err error) bool {
+	return errors.Is(err, unix.EOPNOTSUPP) ||
+		errors.Is(err, unix.ENOTTY) ||
+		errors.Is(err, unix.EINVAL) ||
+		errors.Is(err, unix.EXDEV) ||
+		errors.Is(err, unix.ENOSYS)
+}
+
+// dedupe shares the extents of the src file with the dst file using the FIDEDUPERANGE ioctl.
+// The kernel only shares the extents when the content of both files is identical.
+// It returns the number of bytes shared.
+func dedupe(src, dst string) (int64, error) {
+	s, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer s.Close()
+	d, err := os.OpenFile(dst, os.O_RDWR, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer d.Close()
+	si, err := s.Stat()
+	if err != nil {
+		return 0, err
+	}
+	di, err := d.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if si.Size() != di.Size() {
+		return 0, fmt.Errorf("%w: %s", ErrReflinkDiffers, dst)
+	}
+	n, err := dedupeRange(s, d, uint64(si.Size())) //nolint:gosec
+	return int64(n), err                           //nolint:gosec
+}
+
+// dedupeRange shares the extents of the src file with the dst file in lengths of dedupeLen bytes.
+// It returns the number of bytes shared.
+func dedupeRange(s, d *os.File, size uint64) (uint64, error) {
+	dst, offset := d.Name(), uint64(0)
+	for offset < size {
+		r := unix.FileDedupeRange{
+			Src_offset: offset,
+			Src_length: min(size-offset, dedupeLen),
+			Info: []unix.FileDedupeRangeInfo{{
+				Dest_fd:     int64(d.Fd()), //nolint:gosec
+				Dest_offset: offset,
+			}},
+		}
+		if err := unix.IoctlFileDedupeRange(int(s.Fd()), &r); err != nil { //nolint:gosec
+			if unsupported(err) {
+				return offset, fmt.Errorf("%w: %w", ErrReflink, err)
+			}
+			return offset, err
+		}
+		info := r.Info[0]
+		switch {
+		case info.Status == unix.FILE_DEDUPE_RANGE_DIFFERS:
+			return offset, fmt.Errorf("%w: %s", ErrReflinkDiffers, dst)
+		case info.Status < 0:
+			err := unix.Errno(-info.Status)
+			if unsupported(err) {
+				return offset, fmt.Errorf("%w: %w", ErrReflink, err)
+			}
+			return offset, err
+		case info.Bytes_deduped == 0:
+			return offset, fmt.Errorf("%w: no bytes were shared: %s", ErrReflink, dst)
+		}
+		offset += info.Bytes_deduped
+	}
+	return offset, nil
+}
+
+// clone makes the open dst file a copy-on-write clone of the src file using the FICLONE ioctl.
+func clone(src string, dst *os.File) error {
+	s, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	if err := unix.IoctlFileClone(int(dst.Fd()), int(s.Fd())); err != nil { //nolint:gosec
+		if unsupported(err) {
+			return fmt.Errorf("%w: %w", ErrReflink, err)
+		}
+		return err
+	}
+	return nil
+}
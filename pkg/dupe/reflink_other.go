@@ -0,0 +1,21 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+
+//go:build !linux
+
+package dupe
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// dedupe returns an error as sharing extents is only supported on Linux.
+func dedupe(_, dst string) (int64, error) {
+	return 0, fmt.Errorf("%w: %s: %s", ErrReflink, runtime.GOOS, dst)
+}
+
+// clone returns an error as copy-on-write clones are only supported on Linux.
+func clone(_ string, dst *os.File) error {
+	return fmt.Errorf("%w: %s: %s", ErrReflink, runtime.GOOS, dst.Name())
+}
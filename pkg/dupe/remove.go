@@ -0,0 +1,269 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+package dupe
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bengarrett/dupers/pkg/dupe/journal"
+	"github.com/bengarrett/dupers/pkg/dupe/parse"
+	"github.com/bengarrett/dupers/pkg/dupe/quarantine"
+	"github.com/bengarrett/dupers/pkg/dupe/trash"
+	"github.com/dustin/go-humanize"
+	"github.com/gookit/color"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// tally is the sum of the items and their sizes in bytes.
+type tally struct {
+	items int
+	bytes int64
+}
+
+var (
+	ErrQuarantine = errors.New("the quarantine directory cannot be within the directory to check")
+	ErrSurvivor   = errors.New("duplicate file has no surviving copy")
+	ErrTrash      = errors.New("the quarantine and trash options cannot be used together")
+)
+
+// CheckRemove returns an error if the trash is not supported, if both the quarantine and trash options are set,
+// or if the quarantine directory is within the directory to check.
+func (c *Config) CheckRemove() error {
+	if c.Trash {
+		if c.Quarantine != "" {
+			return ErrTrash
+		}
+		_, err := trash.Dir()
+		return err
+	}
+	if c.Quarantine == "" {
+		return nil
+	}
+	dir, err := filepath.Abs(c.Quarantine)
+	if err != nil {
+		return err
+	}
+	src := c.GetSource()
+	if src == "" {
+		return nil
+	}
+	if dir == src || strings.HasPrefix(dir, src+string(filepath.Separator)) {
+		return fmt.Errorf("%w: %s", ErrQuarantine, dir)
+	}
+	return nil
+}
+
+// Quarantined returns the path of the manifest that lists the files moved to the quarantine directory.
+// It is empty when nothing was moved.
+func (c *Config) Quarantined() string {
+	if c.store == nil || c.store.Count == 0 {
+		return ""
+	}
+	return c.store.Manifest()
+}
+
+// remove deletes the named file or empty directory,
+// or moves it to the quarantine directory or the trash when c.Quarantine or c.Trash is set.
+func (c *Config) remove(name string) error {
+	return c.delete(name, "", parse.Checksum{}, os.Remove)
+}
+
+// removeAll deletes the named path and any children it contains,
+// or moves them to the quarantine directory or the trash when c.Quarantine or c.Trash is set.
+func (c *Config) removeAll(name string) error {
+	return c.delete(name, "", parse.Checksum{}, os.RemoveAll)
+}
+
+// removeDupe deletes the named duplicate file, recording the identical survivor file in the journal.
+// The file is not removed without a survivor, as it would be the last copy.
+func (c *Config) removeDupe(name, survivor string, sum parse.Checksum) error {
+	if survivor == "" || survivor == name {
+		return fmt.Errorf("%w: %s", ErrSurvivor, name)
+	}
+	return c.delete(name, survivor, sum, os.Remove)
+}
+
+// delete the named path using rm, after the removal is written to the journal set by c.Journal.
+func (c *Config) delete(name, survivor string, sum parse.Checksum, rm func(string) error) error {
+	if c.DryRun {
+		return c.dryRun(name)
+	}
+	action := c.discard()
+	if c.Journal != "" {
+		e, err := journal.New(name, survivor, sum)
+		if err != nil {
+			return err
+		}
+		e.Action = action
+		if err := journal.Append(c.Journal, e); err != nil {
+			return err
+		}
+	}
+	var err error
+	switch action {
+	case ActTrashed:
+		err = c.toTrash(name)
+	case ActQuarantined:
+		err = c.toQuarantine(name)
+	default:
+		err = rm(name)
+	}
+	if err != nil {
+		return err
+	}
+	c.act(name, action)
+	return nil
+}
+
+// discard returns the action taken on the removed files, either removed, quarantined or trashed.
+func (c *Config) discard() string {
+	switch {
+	case c.Trash:
+		return ActTrashed
+	case c.Quarantine != "":
+		return ActQuarantined
+	default:
+		return ActRemoved
+	}
+}
+
+// survivor returns the first of the matches that is not the named path and that still exists.
+func survivor(name string, matches ...string) string {
+	for _, match := range matches {
+		if match == name {
+			continue
+		}
+		if _, err := os.Stat(match); err == nil {
+			return match
+		}
+	}
+	return ""
+}
+
+// toQuarantine moves the named path to the dated quarantine directory.
+func (c *Config) toQuarantine(name string) error {
+	if c.store == nil {
+		s, err := quarantine.New(c.Quarantine, time.Now())
+		if err != nil {
+			return err
+		}
+		c.store = s
+	}
+	return c.store.Move(name)
+}
+
+// dryRun records the named path as an item that would be removed, without changing the file system.
+func (c *Config) dryRun(name string) error {
+	n, err := c.du(name)
+	if err != nil {
+		return err
+	}
+	c.dry.items++
+	c.dry.bytes += n
+	c.act(name, ActDryRun)
+	return nil
+}
+
+// dryEmpty returns true when every item within the named directory would be removed by the dry run.
+func (c *Config) dryEmpty(name string) (bool, error) {
+	entries, err := os.ReadDir(name)
+	if err != nil {
+		return false, err
+	}
+	for _, entry := range entries {
+		if c.Action(filepath.Join(name, entry.Name())) == "" {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// du returns the size in bytes of the named file, or the sum of the files within the named directory.
+// Items that the dry run has already counted are skipped.
+func (c *Config) du(name string) (int64, error) {
+	var n int64
+	err := filepath.WalkDir(name, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if c.Action(path) != "" && d.IsDir() {
+			return fs.SkipDir
+		}
+		if c.Action(path) != "" || !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		n += info.Size()
+		return nil
+	})
+	return n, err
+}
+
+// DryRunStatus summarizes the number of items and bytes that the dry run would remove.
+// It is empty when c.DryRun is not set.
+func (c *Config) DryRunStatus() string {
+	if !c.DryRun {
+		return ""
+	}
+	s := color.Info.Sprint("Dry run, nothing was changed.")
+	if c.dry.items == 0 {
+		return s
+	}
+	verb := "removed"
+	switch {
+	case c.Trash:
+		verb = "trashed"
+	case c.Quarantine != "":
+		verb = "quarantined"
+	}
+	p := message.NewPrinter(language.English)
+	return s + p.Sprintf(" %d files and directories, totalling %s, would be %s.",
+		c.dry.items, humanize.Bytes(safesize(c.dry.bytes)), verb)
+}
+
+// toTrash moves the named path to the home trash directory.
+func (c *Config) toTrash(name string) error {
+	if c.can == nil {
+		can, err := trash.New()
+		if err != nil {
+			return err
+		}
+		c.can = can
+	}
+	dst, err := c.can.Put(name, time.Now())
+	if err != nil {
+		return err
+	}
+	c.Debugger("trashed file: " + dst)
+	return nil
+}
+
+// printRM prints "removed:", "quarantined:" or "trashed:" for the path, or prints any error to stderr.
+// A dry run instead prints "would remove:", "would quarantine:" or "would trash:".
+func (c *Config) printRM(path string, err error) string {
+	switch {
+	case err != nil:
+		return PrintRM(path, err)
+	case c.DryRun && c.Trash:
+		return fmt.Sprintf("%s: %s", color.Secondary.Sprint("would trash"), path)
+	case c.DryRun && c.Quarantine != "":
+		return fmt.Sprintf("%s: %s", color.Secondary.Sprint("would quarantine"), path)
+	case c.DryRun:
+		return fmt.Sprintf("%s: %s", color.Secondary.Sprint("would remove"), path)
+	case c.Trash:
+		return fmt.Sprintf("%s: %s", color.Secondary.Sprint("trashed"), path)
+	case c.Quarantine != "":
+		return fmt.Sprintf("%s: %s", color.Secondary.Sprint("quarantined"), path)
+	default:
+		return PrintRM(path, err)
+	}
+}
@@ -0,0 +1,254 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+package dupe
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bengarrett/dupers/pkg/database"
+	"github.com/bengarrett/dupers/pkg/database/record"
+	bolt "go.etcd.io/bbolt"
+	bberr "go.etcd.io/bbolt/errors"
+)
+
+// Output formats for the machine-readable results.
+const (
+	CSV    = "csv"    // CSV is comma-separated values with a header row and a row for each match.
+	JSON   = "json"   // JSON is an array of result objects.
+	NDJSON = "ndjson" // NDJSON is newline delimited JSON with a result object on each line.
+)
+
+// Actions taken on a source file.
+const (
+	ActRemoved     = "removed"     // ActRemoved is a deleted file or directory.
+	ActQuarantined = "quarantined" // ActQuarantined is a file or directory moved to the quarantine directory.
+	ActTrashed     = "trashed"     // ActTrashed is a file or directory moved to the desktop trash.
+	ActLinked      = "linked"      // ActLinked is a file replaced by a link to an identical file.
+	ActReflinked   = "reflinked"   // ActReflinked is a file that shares its data extents with an identical file.
+	ActDryRun      = "dry run"     // ActDryRun is a file or directory that would be acted on, but was left untouched.
+)
+
+var ErrFormat = errors.New("unknown output format, use either json, ndjson or csv")
+
+// Result is a machine-readable record of a dupe or search match.
+type Result struct {
+	Source   string   `json:"source,omitempty"` // Source is the file that was checked.
+	Matches  []string `json:"matches"`          // Matches are the paths of the identical or found files.
+	Bucket   string   `json:"bucket,omitempty"` // Bucket containing the matches.
+	Checksum string   `json:"checksum"`         // Checksum is the SHA256 hash as a hexadecimal string.
+	Size     int64    `json:"size"`             // Size of the file in bytes.
+	Action   string   `json:"action,omitempty"` // Action taken on the source, such as removed.
+}
+
+// CheckFormat returns an error if the named output format is unknown.
+// An empty format is valid and means the results use the colored text output.
+func CheckFormat(name string) error {
+	switch strings.ToLower(name) {
+	case "", CSV, JSON, NDJSON:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrFormat, name)
+	}
+}
+
+// Results returns the matches of a dupe request as machine-readable records.
+// A source file with matches in multiple buckets returns a record for each bucket.
+func (c *Config) Results() ([]Result, error) {
+	files, err := c.sourceFiles()
+	if err != nil {
+		return nil, err
+	}
+	files = c.prune(files...)
+	sums, err := c.sums(files...)
+	if err != nil {
+		return nil, err
+	}
+	results := []Result{}
+	for i, path := range files {
+		matches := c.matches(path, sums[i])
+		if len(matches) == 0 {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		buckets, group := []string{}, map[string][]string{}
+		for _, match := range matches {
+			b := c.bucketOf(match)
+			if _, ok := group[b]; !ok {
+				buckets = append(buckets, b)
+			}
+			group[b] = append(group[b], match)
+		}
+		for _, b := range buckets {
+			results = append(results, Result{
+				Source:   path,
+				Matches:  group[b],
+				Bucket:   b,
+				Checksum: hex.EncodeToString(sums[i][:]),
+				Size:     info.Size(),
+			})
+		}
+	}
+	return results, nil
+}
+
+// SetActions sets the action taken on the source of each result.
+func (c *Config) SetActions(results ...Result) {
+	for i := range results {
+		results[i].Action = c.Action(results[i].Source)
+	}
+}
+
+// Action returns the action taken on the path or any of its parent directories.
+func (c *Config) Action(path string) string {
+	if path == "" {
+		return ""
+	}
+	for {
+		if a, ok := c.actions[path]; ok {
+			return a
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return ""
+		}
+		path = parent
+	}
+}
+
+// act records the action taken on the path.
+func (c *Config) act(path, action string) {
+	if c.actions == nil {
+		c.actions = make(map[string]string)
+	}
+	c.actions[path] = action
+}
+
+// GroupResults returns the groups of identical files found by Self as machine-readable records.
+func GroupResults(groups ...Group) []Result {
+	results := make([]Result, 0, len(groups))
+	for _, g := range groups {
+		results = append(results, Result{
+			Source:   g.Paths[0],
+			Matches:  g.Paths[1:],
+			Checksum: hex.EncodeToString(g.Sum[:]),
+			Size:     g.Size,
+		})
+	}
+	return results
+}
+
+// SearchResults returns the search matches as machine-readable records sorted by bucket and path.
+// The checksum and size of each match are read from the database.
+func SearchResults(db *bolt.DB, m *database.Matches) ([]Result, error) {
+	if db == nil {
+		return nil, bberr.ErrDatabaseNotOpen
+	}
+	results := []Result{}
+	if m == nil {
+		return results, nil
+	}
+	err := db.View(func(tx *bolt.Tx) error {
+		for path, bucket := range *m {
+			b := tx.Bucket([]byte(bucket))
+			if b == nil {
+				return fmt.Errorf("%w: %s", bberr.ErrBucketNotFound, bucket)
+			}
+			r, err := record.Decode(b.Get([]byte(path)))
+			if err != nil {
+				return fmt.Errorf("%w: %s", err, path)
+			}
+			results = append(results, Result{
+				Matches:  []string{string(path)},
+				Bucket:   string(bucket),
+				Checksum: hex.EncodeToString(r.Sum[:]),
+				Size:     r.Size,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(results, func(a, b Result) int {
+		if n := strings.Compare(a.Bucket, b.Bucket); n != 0 {
+			return n
+		}
+		return strings.Compare(a.Matches[0], b.Matches[0])
+	})
+	return results, nil
+}
+
+// RankResults returns the results of the fuzzy search, keeping the ranked order of the matches.
+func RankResults(db *bolt.DB, r database.Ranks) ([]Result, error) {
+	results, err := SearchResults(db, r.Matches())
+	if err != nil {
+		return nil, err
+	}
+	order := make(map[string]int, len(r))
+	for i, rank := range r {
+		order[string(rank.Path)] = i
+	}
+	slices.SortFunc(results, func(a, b Result) int {
+		return cmp.Compare(order[a.Matches[0]], order[b.Matches[0]])
+	})
+	return results, nil
+}
+
+// WriteResults writes the results to w using the named output format.
+func WriteResults(w io.Writer, format string, results ...Result) error {
+	if w == nil {
+		w = io.Discard
+	}
+	switch strings.ToLower(format) {
+	case CSV:
+		return writeCSV(w, results...)
+	case JSON:
+		if results == nil {
+			results = []Result{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	case NDJSON:
+		enc := json.NewEncoder(w)
+		for _, r := range results {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrFormat, format)
+	}
+}
+
+// writeCSV writes the results as comma-separated values with a row for each match.
+func writeCSV(w io.Writer, results ...Result) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"source", "match", "bucket", "checksum", "size", "action"}); err != nil {
+		return err
+	}
+	for _, r := range results {
+		size := strconv.FormatInt(r.Size, 10)
+		for _, match := range r.Matches {
+			if err := cw.Write([]string{r.Source, match, r.Bucket, r.Checksum, size, r.Action}); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
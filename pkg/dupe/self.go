@@ -0,0 +1,114 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+package dupe
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/bengarrett/dupers/pkg/dupe/parse"
+	"github.com/gookit/color"
+)
+
+// Group is a collection of identical files that share a checksum.
+type Group struct {
+	Sum   parse.Checksum // Sum is the SHA256 checksum shared by the files.
+	Size  int64          // Size of each file in bytes.
+	Paths []string       // Paths of the identical files, sorted by name.
+}
+
+// Self finds every group of identical files within the source without using the database.
+// The files are first grouped by size and partial checksum, so only possible duplicates are fully hashed.
+// Empty files are ignored unless c.Empty is set, as are files outside of the minimum and maximum sizes.
+func (c *Config) Self() ([]Group, error) {
+	c.Debugger("find identical files within the source.")
+	sizes := make(map[int64][]string)
+	for _, name := range c.Sources {
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() || !c.sized(info.Size()) {
+			continue
+		}
+		c.Files++
+		sizes[info.Size()] = append(sizes[info.Size()], name)
+	}
+	files := []string{}
+	for size, names := range sizes {
+		if len(names) < 2 {
+			continue
+		}
+		if size <= parse.PartSize*2 {
+			files = append(files, names...)
+			continue
+		}
+		files = append(files, partials(names...)...)
+	}
+	c.Debugger(fmt.Sprintf("self check hashes %d of %d files", len(files), c.Files))
+	sums, err := c.sums(files...)
+	if err != nil {
+		return nil, err
+	}
+	groups := make(map[parse.Checksum]*Group)
+	for i, name := range files {
+		g, ok := groups[sums[i]]
+		if !ok {
+			info, err := os.Stat(name)
+			if err != nil {
+				return nil, err
+			}
+			g = &Group{Sum: sums[i], Size: info.Size()}
+			groups[sums[i]] = g
+		}
+		g.Paths = append(g.Paths, name)
+	}
+	s := make([]Group, 0, len(groups))
+	for _, g := range groups {
+		if len(g.Paths) < 2 {
+			continue
+		}
+		slices.Sort(g.Paths)
+		s = append(s, *g)
+	}
+	slices.SortFunc(s, func(a, b Group) int {
+		return strings.Compare(a.Paths[0], b.Paths[0])
+	})
+	return s, nil
+}
+
+// partials returns the named files that share a partial checksum with another named file.
+// Files that cannot be read are always returned.
+func partials(names ...string) []string {
+	parts := make(map[parse.Checksum][]string)
+	files := []string{}
+	for _, name := range names {
+		sum, err := parse.ReadPartial(name)
+		if err != nil {
+			files = append(files, name)
+			continue
+		}
+		parts[sum] = append(parts[sum], name)
+	}
+	for _, names := range parts {
+		if len(names) < 2 {
+			continue
+		}
+		files = append(files, names...)
+	}
+	return files
+}
+
+// PrintSelf prints the groups of identical files found by Self.
+func PrintSelf(groups ...Group) string {
+	w := new(bytes.Buffer)
+	for _, g := range groups {
+		printl(w, Match(g.Paths[0], g.Paths[1:]...))
+	}
+	if len(groups) == 0 {
+		printl(w, color.Info.Sprint("\rNo duplicate files found.          "))
+	}
+	return w.String()
+}
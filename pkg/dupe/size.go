@@ -0,0 +1,109 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+package dupe
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+var (
+	ErrFileLarge = errors.New("file is larger than the maximum size")
+	ErrFileSmall = errors.New("file is smaller than the minimum size")
+	ErrSize      = errors.New("size is invalid, use a number of bytes or a unit such as 4KiB or 2GB")
+	ErrSizes     = errors.New("the minimum size cannot be larger than the maximum size")
+)
+
+// skips are the number of files skipped by their size.
+type skips struct {
+	empty int // empty is the number of zero byte files.
+	small int // small is the number of files smaller than the minimum size.
+	large int // large is the number of files larger than the maximum size.
+}
+
+// ParseSize returns the number of bytes of the humanized size, such as 500, 4KiB or 2GB.
+// An empty string returns zero.
+func ParseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	i, err := humanize.ParseBytes(s)
+	if err != nil || i > math.MaxInt64 {
+		return 0, fmt.Errorf("%w: %q", ErrSize, s)
+	}
+	return int64(i), nil
+}
+
+// CheckSize returns an error if the minimum or maximum sizes are negative,
+// or if the minimum size is larger than the maximum size.
+func (c *Config) CheckSize() error {
+	if c.MinSize < 0 || c.MaxSize < 0 {
+		return ErrSize
+	}
+	if c.MaxSize > 0 && c.MinSize > c.MaxSize {
+		return fmt.Errorf("%w: %s > %s", ErrSizes,
+			humanize.IBytes(safesize(c.MinSize)), humanize.IBytes(safesize(c.MaxSize)))
+	}
+	return nil
+}
+
+// checkSize returns an error if the file size is outside of the minimum and maximum sizes,
+// or if the file is zero bytes and c.Empty is false.
+func (c *Config) checkSize(size int64) error {
+	switch {
+	case size == 0 && !c.Empty:
+		return ErrFileEmpty
+	case size < c.MinSize:
+		return ErrFileSmall
+	case c.MaxSize > 0 && size > c.MaxSize:
+		return ErrFileLarge
+	}
+	return nil
+}
+
+// sized returns true if the file size is accepted by checkSize, otherwise the skipped file is counted.
+func (c *Config) sized(size int64) bool {
+	err := c.checkSize(size)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, ErrFileEmpty):
+		c.skips.empty++
+	case errors.Is(err, ErrFileSmall):
+		c.skips.small++
+	case errors.Is(err, ErrFileLarge):
+		c.skips.large++
+	}
+	return false
+}
+
+// Skipped returns a summary of the files skipped by their size, or an empty string if there are none.
+func (c *Config) Skipped() string {
+	p := message.NewPrinter(language.English)
+	files := func(n int, kind string) string {
+		if n == 1 {
+			return p.Sprintf("%d %sfile", n, kind)
+		}
+		return p.Sprintf("%d %sfiles", n, kind)
+	}
+	s := []string{}
+	if n := c.skips.empty; n > 0 {
+		s = append(s, files(n, "empty "))
+	}
+	if n := c.skips.small; n > 0 {
+		s = append(s, files(n, "")+" smaller than "+humanize.IBytes(safesize(c.MinSize)))
+	}
+	if n := c.skips.large; n > 0 {
+		s = append(s, files(n, "")+" larger than "+humanize.IBytes(safesize(c.MaxSize)))
+	}
+	if len(s) == 0 {
+		return ""
+	}
+	return "skipped " + strings.Join(s, ", ")
+}
@@ -0,0 +1,155 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+
+// Package trash moves files into the home trash directory of the freedesktop.org Trash specification.
+// The trashed files can then be viewed and restored using a desktop file manager.
+//
+// https://specifications.freedesktop.org/trash-spec/latest/
+package trash
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bengarrett/dupers/internal/file"
+)
+
+const (
+	// Ext is the file extension of the trash information files.
+	Ext = ".trashinfo"
+	// Layout is the time format of the deletion date.
+	Layout = "2006-01-02T15:04:05"
+
+	maxNames = 10000
+)
+
+var (
+	ErrOS     = errors.New("the trash option is not supported on this operating system")
+	ErrHome   = errors.New("could not find the home directory for the trash")
+	ErrName   = errors.New("could not create a unique name in the trash")
+	ErrDevice = errors.New("the file is on a different file system to the home trash, use the quarantine option instead")
+)
+
+// Can is a trash directory that contains the files and info subdirectories.
+type Can struct {
+	Root string // Root is the trash directory.
+}
+
+// Dir returns the home trash directory, $XDG_DATA_HOME/Trash or ~/.local/share/Trash.
+func Dir() (string, error) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		return "", fmt.Errorf("%w: %s", ErrOS, runtime.GOOS)
+	}
+	if dir := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "Trash"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return "", fmt.Errorf("%w: %w", ErrHome, err)
+	}
+	return filepath.Join(home, ".local", "share", "Trash"), nil
+}
+
+// New returns the home trash.
+func New() (*Can, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	return &Can{Root: dir}, nil
+}
+
+// Files returns the directory that contains the trashed files.
+func (c *Can) Files() string {
+	return filepath.Join(c.Root, "files")
+}
+
+// Info returns the directory that contains the trash information files.
+func (c *Can) Info() string {
+	return filepath.Join(c.Root, "info")
+}
+
+// Put moves the named file or directory into the trash together with a trash information file
+// that records the original path and the deletion date.
+// A file on a different file system to the trash is never copied, and returns ErrDevice.
+// It returns the path of the trashed file.
+func (c *Can) Put(name string, deleted time.Time) (string, error) {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Lstat(abs); err != nil {
+		return "", err
+	}
+	for _, dir := range []string{c.Files(), c.Info()} {
+		if err := os.MkdirAll(dir, file.PrivateDir); err != nil {
+			return "", err
+		}
+	}
+	info, base, err := c.info(abs, deleted)
+	if err != nil {
+		return "", err
+	}
+	dst := filepath.Join(c.Files(), base)
+	if err := os.Rename(abs, dst); err != nil {
+		_ = os.Remove(info)
+		if errors.Is(err, syscall.EXDEV) {
+			return "", fmt.Errorf("%w: %s", ErrDevice, abs)
+		}
+		return "", err
+	}
+	return dst, nil
+}
+
+// info creates a trash information file for the absolute path using a name that is unique to the trash.
+// The info file is created before the file is moved, as required by the specification.
+// It returns the path of the info file and the unique name.
+func (c *Can) info(abs string, deleted time.Time) (string, string, error) {
+	body := Info(abs, deleted)
+	ext := filepath.Ext(abs)
+	stem := strings.TrimSuffix(filepath.Base(abs), ext)
+	for i := 1; i <= maxNames; i++ {
+		base := filepath.Base(abs)
+		if i > 1 {
+			base = stem + "." + strconv.Itoa(i) + ext
+		}
+		if _, err := os.Lstat(filepath.Join(c.Files(), base)); err == nil {
+			continue
+		}
+		path := filepath.Join(c.Info(), base+Ext)
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, file.PrivateFile)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", "", err
+		}
+		if _, err := f.WriteString(body); err != nil {
+			_ = f.Close()
+			_ = os.Remove(path)
+			return "", "", err
+		}
+		if err := f.Close(); err != nil {
+			_ = os.Remove(path)
+			return "", "", err
+		}
+		return path, base, nil
+	}
+	return "", "", fmt.Errorf("%w: %s", ErrName, abs)
+}
+
+// Info returns the content of a trash information file for the absolute path.
+// The path is percent-encoded and the deletion date uses the local time without a time zone.
+func Info(abs string, deleted time.Time) string {
+	u := url.URL{Path: filepath.ToSlash(abs)}
+	return fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		u.EscapedPath(), deleted.Local().Format(Layout))
+}
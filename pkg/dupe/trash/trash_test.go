@@ -0,0 +1,79 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+package trash_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/bengarrett/dupers/internal/mock"
+	"github.com/bengarrett/dupers/pkg/dupe/trash"
+	"github.com/nalgeon/be"
+)
+
+func TestInfo(t *testing.T) {
+	deleted := time.Date(2004, 8, 31, 22, 32, 8, 0, time.Local)
+	s := trash.Info("/home/user/a file%.txt", deleted)
+	be.Equal(t, s, "[Trash Info]\nPath=/home/user/a%20file%25.txt\nDeletionDate=2004-08-31T22:32:08\n")
+}
+
+func TestCan_Put(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		_, err := trash.New()
+		be.Err(t, err, trash.ErrOS)
+		return
+	}
+	data := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", data)
+	can, err := trash.New()
+	be.Err(t, err, nil)
+	be.Equal(t, can.Root, filepath.Join(data, "Trash"))
+
+	src := t.TempDir()
+	deleted := time.Now()
+	for i, want := range []string{"file.txt", "file.2.txt"} {
+		name := filepath.Join(src, "file.txt")
+		err := os.WriteFile(name, []byte{byte(i)}, mock.PrivateFile)
+		be.Err(t, err, nil)
+		dst, err := can.Put(name, deleted)
+		be.Err(t, err, nil)
+		be.Equal(t, dst, filepath.Join(can.Files(), want))
+		_, err = os.Stat(name)
+		be.Err(t, err, os.ErrNotExist)
+		b, err := os.ReadFile(filepath.Join(can.Info(), want+trash.Ext))
+		be.Err(t, err, nil)
+		be.Equal(t, string(b), trash.Info(name, deleted))
+	}
+	_, err = can.Put(filepath.Join(src, "missing"), deleted)
+	be.Err(t, err, os.ErrNotExist)
+}
+
+func TestCan_PutDevice(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the trash is only tested on linux")
+	}
+	shm, err := os.MkdirTemp("/dev/shm", "dupers-*")
+	if err != nil {
+		t.Skip("the test needs the /dev/shm memory file system")
+	}
+	defer os.RemoveAll(shm)
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	can, err := trash.New()
+	be.Err(t, err, nil)
+	name := filepath.Join(shm, "file.txt")
+	err = os.WriteFile(name, []byte("trash"), mock.PrivateFile)
+	be.Err(t, err, nil)
+	_, err = can.Put(name, time.Now())
+	if err == nil {
+		t.Skip("the temporary directory and /dev/shm are on the same file system")
+	}
+	be.Err(t, err, trash.ErrDevice)
+	// the file is kept and the information file is removed
+	_, err = os.Stat(name)
+	be.Err(t, err, nil)
+	entries, err := os.ReadDir(can.Info())
+	be.Err(t, err, nil)
+	be.Equal(t, len(entries), 0)
+}
@@ -0,0 +1,281 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+
+// Package tree finds identical and subset directories using a Merkle-style digest of each directory,
+// which is calculated from the names and checksums of its files and the digests of its subdirectories.
+package tree
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bengarrett/dupers/pkg/dupe/parse"
+)
+
+const sep = string(filepath.Separator)
+
+// Dirs is a group of identical directories.
+type Dirs struct {
+	Paths []string // Paths of the identical directories, sorted by name.
+	Files int      // Files is the number of files within each directory, including its subdirectories.
+	Size  int64    // Size is the sum of the files within each directory in bytes.
+}
+
+// Subset is a directory with every file also stored using the same relative path and checksum in another directory.
+type Subset struct {
+	Path  string // Path of the subset directory.
+	Of    string // Of is the path of the directory that contains every file of the subset.
+	Files int    // Files is the number of files within the subset directory.
+	Size  int64  // Size is the sum of the files within the subset directory in bytes.
+}
+
+type file struct {
+	path string
+	sum  parse.Checksum
+	size int64
+}
+
+type node struct {
+	files    map[string]parse.Checksum // files are the names and checksums of the files in the directory.
+	dirs     []string                  // dirs are the paths of the subdirectories.
+	digest   parse.Checksum            // digest of the directory content.
+	lo, hi   int                       // lo and hi are the range of the files within the directory and subdirectories.
+	size     int64                     // size of the files within the directory and subdirectories.
+	parent   string                    // parent is the path of the parent directory, it is empty for a root.
+	complete bool                      // complete is true once the digest is calculated.
+}
+
+// Tree is a collection of files within one or more root directories.
+type Tree struct {
+	files []file
+	dirs  map[string]*node
+	index map[string]int              // index of the sorted files by their path.
+	sums  map[parse.Checksum][]string // sums are the paths of the files that share a checksum.
+}
+
+// New returns an empty tree.
+func New() *Tree {
+	return &Tree{dirs: make(map[string]*node)}
+}
+
+// Add the named file with its checksum and size to the tree.
+// The directories between the root and the file are added to the tree,
+// while files that are not within the root directory are ignored.
+func (t *Tree) Add(root, name string, sum parse.Checksum, size int64) {
+	root, name = filepath.Clean(root), filepath.Clean(name)
+	if !strings.HasPrefix(name, strings.TrimSuffix(root, sep)+sep) {
+		return
+	}
+	t.files = append(t.files, file{path: name, sum: sum, size: size})
+	t.index = nil
+	dir := filepath.Dir(name)
+	n := t.node(dir)
+	n.files[filepath.Base(name)] = sum
+	for dir != root {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return
+		}
+		if n.parent != "" {
+			// the parent directories are already in the tree
+			return
+		}
+		n.parent = parent
+		p := t.node(parent)
+		p.dirs = append(p.dirs, dir)
+		dir, n = parent, p
+	}
+}
+
+// node returns the named directory, adding it to the tree when it is new.
+func (t *Tree) node(dir string) *node {
+	n, ok := t.dirs[dir]
+	if !ok {
+		n = &node{files: make(map[string]parse.Checksum)}
+		t.dirs[dir] = n
+	}
+	return n
+}
+
+// build sorts the files and calculates the digest of every directory.
+func (t *Tree) build() {
+	if t.index != nil {
+		return
+	}
+	slices.SortFunc(t.files, func(a, b file) int {
+		return strings.Compare(a.path, b.path)
+	})
+	t.files = slices.CompactFunc(t.files, func(a, b file) bool {
+		return a.path == b.path
+	})
+	t.index = make(map[string]int, len(t.files))
+	t.sums = make(map[parse.Checksum][]string)
+	for i, f := range t.files {
+		t.index[f.path] = i
+		t.sums[f.sum] = append(t.sums[f.sum], f.path)
+	}
+	for dir, n := range t.dirs {
+		prefix := dir + sep
+		n.lo, _ = slices.BinarySearchFunc(t.files, prefix, func(f file, s string) int {
+			return strings.Compare(f.path, s)
+		})
+		n.hi = n.lo
+		n.size = 0
+		for n.hi < len(t.files) && strings.HasPrefix(t.files[n.hi].path, prefix) {
+			n.size += t.files[n.hi].size
+			n.hi++
+		}
+		n.complete = false
+	}
+	for dir := range t.dirs {
+		t.digest(dir)
+	}
+}
+
+// digest calculates the digest of the named directory from the digests of its subdirectories.
+func (t *Tree) digest(dir string) parse.Checksum {
+	n := t.dirs[dir]
+	if n.complete {
+		return n.digest
+	}
+	lines := make([]string, 0, len(n.files)+len(n.dirs))
+	for name, sum := range n.files {
+		lines = append(lines, "f\x00"+name+"\x00"+string(sum[:]))
+	}
+	for _, sub := range n.dirs {
+		sum := t.digest(sub)
+		lines = append(lines, "d\x00"+filepath.Base(sub)+"\x00"+string(sum[:]))
+	}
+	slices.Sort(lines)
+	h := sha256.New()
+	for _, line := range lines {
+		_, _ = h.Write([]byte(line + "\n"))
+	}
+	copy(n.digest[:], h.Sum(nil))
+	n.complete = true
+	return n.digest
+}
+
+// count returns the number of files within the directory and its subdirectories.
+func (n *node) count() int {
+	return n.hi - n.lo
+}
+
+// Identical returns the groups of directories with identical names and content, sorted by size.
+// A group is skipped when the parents of its directories are also identical to each other, as the parents are reported instead.
+func (t *Tree) Identical() []Dirs {
+	t.build()
+	groups := make(map[parse.Checksum][]string)
+	for dir, n := range t.dirs {
+		if n.count() == 0 {
+			continue
+		}
+		groups[n.digest] = append(groups[n.digest], dir)
+	}
+	s := []Dirs{}
+	for _, paths := range groups {
+		if len(paths) < 2 || t.covered(groups, paths...) {
+			continue
+		}
+		slices.Sort(paths)
+		n := t.dirs[paths[0]]
+		s = append(s, Dirs{Paths: paths, Files: n.count(), Size: n.size})
+	}
+	slices.SortFunc(s, func(a, b Dirs) int {
+		if c := cmp.Compare(b.Size, a.Size); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Paths[0], b.Paths[0])
+	})
+	return s
+}
+
+// covered returns true when the parents of every path are members of the same group of identical directories.
+// Parents in different groups do not report the paths as duplicates of each other, so they are not covered.
+func (t *Tree) covered(groups map[parse.Checksum][]string, paths ...string) bool {
+	var digest parse.Checksum
+	for i, path := range paths {
+		p, ok := t.dirs[t.dirs[path].parent]
+		if !ok || len(groups[p.digest]) < 2 {
+			return false
+		}
+		if i > 0 && p.digest != digest {
+			return false
+		}
+		digest = p.digest
+	}
+	return true
+}
+
+// Subsets returns the directories that have every file stored within a larger directory, sorted by size.
+// The files must share both their relative paths and checksums.
+// A subset is skipped when its parent is also a subset of, or identical to, the parent of the larger directory.
+func (t *Tree) Subsets() []Subset {
+	t.build()
+	pairs := make(map[[2]string]bool)
+	for dir, n := range t.dirs {
+		for _, of := range t.candidates(dir, n) {
+			pairs[[2]string{dir, of}] = true
+		}
+	}
+	s := []Subset{}
+	for pair := range pairs {
+		dir, of := pair[0], pair[1]
+		n := t.dirs[dir]
+		pa, pb := n.parent, t.dirs[of].parent
+		if pa != "" && pb != "" && filepath.Base(dir) == filepath.Base(of) &&
+			(pairs[[2]string{pa, pb}] || t.dirs[pa].digest == t.dirs[pb].digest) {
+			continue
+		}
+		s = append(s, Subset{Path: dir, Of: of, Files: n.count(), Size: n.size})
+	}
+	slices.SortFunc(s, func(a, b Subset) int {
+		if c := cmp.Compare(b.Size, a.Size); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.Path, b.Path); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Of, b.Of)
+	})
+	return s
+}
+
+// candidates returns the larger directories that contain every file of the named directory.
+func (t *Tree) candidates(dir string, n *node) []string {
+	if n.count() == 0 {
+		return nil
+	}
+	first := t.files[n.lo]
+	rel := strings.TrimPrefix(first.path, dir+sep)
+	s := []string{}
+	for _, path := range t.sums[first.sum] {
+		if !strings.HasSuffix(path, sep+rel) {
+			continue
+		}
+		of := strings.TrimSuffix(path, sep+rel)
+		m, ok := t.dirs[of]
+		if !ok || of == dir || m.count() <= n.count() || m.digest == n.digest ||
+			strings.HasPrefix(dir, of+sep) || strings.HasPrefix(of, dir+sep) {
+			continue
+		}
+		if t.within(dir, of) {
+			s = append(s, of)
+		}
+	}
+	return s
+}
+
+// within returns true when every file of the named directory exists in the other directory.
+func (t *Tree) within(dir, of string) bool {
+	n := t.dirs[dir]
+	for _, f := range t.files[n.lo:n.hi] {
+		i, ok := t.index[of+strings.TrimPrefix(f.path, dir)]
+		if !ok || t.files[i].sum != f.sum {
+			return false
+		}
+	}
+	return true
+}
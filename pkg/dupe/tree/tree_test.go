@@ -0,0 +1,72 @@
+// © Ben Garrett https://github.com/bengarrett/dupers
+package tree_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/bengarrett/dupers/pkg/dupe/parse"
+	"github.com/bengarrett/dupers/pkg/dupe/tree"
+	"github.com/nalgeon/be"
+)
+
+func path(elem ...string) string {
+	return filepath.Join(append([]string{string(filepath.Separator)}, elem...)...)
+}
+
+func TestTree(t *testing.T) {
+	s1, s2, s3, s4 := parse.Checksum{1}, parse.Checksum{2}, parse.Checksum{3}, parse.Checksum{4}
+	b1, b2 := path("b1"), path("b2")
+	x := tree.New()
+	x.Add(b1, path("b1", "other.txt"), s4, 40)
+	x.Add(b1, path("b1", "photos", "a.jpg"), s1, 10)
+	x.Add(b1, path("b1", "photos", "b.jpg"), s2, 20)
+	x.Add(b1, path("b1", "photos", "sub", "c.jpg"), s3, 30)
+	x.Add(b2, path("b2", "backup", "photos", "a.jpg"), s1, 10)
+	x.Add(b2, path("b2", "backup", "photos", "b.jpg"), s2, 20)
+	x.Add(b2, path("b2", "backup", "photos", "sub", "c.jpg"), s3, 30)
+	x.Add(b2, path("b2", "old", "photos", "a.jpg"), s1, 10)
+	// files outside of the root are ignored
+	x.Add(b2, path("b3", "photos", "a.jpg"), s1, 10)
+
+	dirs := x.Identical()
+	be.Equal(t, len(dirs), 1)
+	be.Equal(t, dirs[0].Paths, []string{path("b1", "photos"), path("b2", "backup", "photos")})
+	be.Equal(t, dirs[0].Files, 3)
+	be.Equal(t, dirs[0].Size, int64(60))
+
+	// the subdirectories of a subset are not reported
+	subsets := x.Subsets()
+	be.Equal(t, len(subsets), 3)
+	be.Equal(t, subsets[0], tree.Subset{Path: path("b2", "backup"), Of: b1, Files: 3, Size: 60})
+	be.Equal(t, subsets[1], tree.Subset{Path: path("b2", "old"), Of: b1, Files: 1, Size: 10})
+	be.Equal(t, subsets[2], tree.Subset{Path: path("b2", "old"), Of: path("b2", "backup"), Files: 1, Size: 10})
+
+	// a renamed file breaks both the digest and the subset
+	y := tree.New()
+	y.Add(b1, path("b1", "a", "a.jpg"), s1, 10)
+	y.Add(b1, path("b1", "b", "renamed.jpg"), s1, 10)
+	be.Equal(t, len(y.Identical()), 0)
+	be.Equal(t, len(y.Subsets()), 0)
+}
+
+func TestTree_ParentGroups(t *testing.T) {
+	s1, s2, s3, s4 := parse.Checksum{1}, parse.Checksum{2}, parse.Checksum{3}, parse.Checksum{4}
+	x := tree.New()
+	x.Add(path("b1"), path("b1", "unique.txt"), s4, 40)
+	for _, root := range []string{"b1", "b2"} {
+		x.Add(path(root), path(root, "x", "a", "a.jpg"), s1, 10)
+		x.Add(path(root), path(root, "x", "x.jpg"), s2, 20)
+		x.Add(path(root), path(root, "y", "a", "a.jpg"), s1, 10)
+		x.Add(path(root), path(root, "y", "y.jpg"), s3, 30)
+	}
+	// x/a and y/a are identical, but their parents x and y are in different groups
+	dirs := x.Identical()
+	be.Equal(t, len(dirs), 3)
+	be.Equal(t, dirs[0].Paths, []string{path("b1", "y"), path("b2", "y")})
+	be.Equal(t, dirs[1].Paths, []string{path("b1", "x"), path("b2", "x")})
+	be.Equal(t, dirs[2].Paths, []string{
+		path("b1", "x", "a"), path("b1", "y", "a"),
+		path("b2", "x", "a"), path("b2", "y", "a"),
+	})
+}